@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ogen-go/ogen/ogenerrors"
 	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
@@ -20,6 +22,15 @@ type Client struct {
 	tokenSource    TokenSource
 	userAgent      string
 	requestEditors []apispec.RequestEditor
+	// cancelGracePeriod is the time between signals sent on cancellation.
+	cancelGracePeriod time.Duration
+	// onCancelError receives the errors of signals sent on cancellation.
+	onCancelError func(contextID string, err error)
+	// cancellations tracks the signals sent in the background on cancellation.
+	cancellations sync.WaitGroup
+
+	languagesMu sync.RWMutex
+	languages   map[string]apispec.REPLConfig
 }
 
 // NewClient creates a new Sandbox0 SDK client.
@@ -34,10 +45,12 @@ func NewClient(opts ...Option) (*Client, error) {
 	}
 
 	client := &Client{
-		baseURL:        cfg.baseURL,
-		tokenSource:    cfg.tokenSource,
-		userAgent:      cfg.userAgent,
-		requestEditors: cfg.requestEditors,
+		baseURL:           cfg.baseURL,
+		tokenSource:       cfg.tokenSource,
+		userAgent:         cfg.userAgent,
+		requestEditors:    cfg.requestEditors,
+		cancelGracePeriod: cfg.cancelGrace,
+		onCancelError:     cfg.onCancelError,
 	}
 
 	var clientOpts []apispec.ClientOption
@@ -69,9 +82,11 @@ func (c *Client) API() *apispec.Client {
 // Sandbox returns a convenience wrapper for a known sandbox ID.
 func (c *Client) Sandbox(id string) *Sandbox {
 	return &Sandbox{
-		ID:                id,
-		client:            c,
-		replContextByLang: map[string]string{},
+		ID:           id,
+		client:       c,
+		replContexts: map[string]string{},
+		replStreams:  map[string]*ContextStream{},
+		jobs:         map[string]*Job{},
 	}
 }
 
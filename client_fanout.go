@@ -0,0 +1,274 @@
+package sandbox0
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultFanOutConcurrency bounds the tasks FanOut runs at once.
+	defaultFanOutConcurrency = 10
+	// fanOutCleanupTimeout bounds the deletion of each sandbox claimed by FanOut.
+	fanOutCleanupTimeout = 30 * time.Second
+)
+
+// FanOutResult is the outcome of one FanOut task.
+type FanOutResult[T any] struct {
+	// Index is the position of the task: the index in the sandboxes passed to
+	// FanOut, followed by the sandboxes claimed with WithFanOutClaim.
+	Index     int
+	SandboxID string
+	Value     T
+	// Err is the error of the last attempt, or nil when the task succeeded.
+	Err      error
+	Attempts int
+	Duration time.Duration
+}
+
+// FanOutEventType identifies a FanOut progress event.
+type FanOutEventType string
+
+const (
+	FanOutStarted  FanOutEventType = "started"
+	FanOutRetrying FanOutEventType = "retrying"
+	FanOutFinished FanOutEventType = "finished"
+)
+
+// FanOutEvent reports the progress of a FanOut task.
+type FanOutEvent struct {
+	Type      FanOutEventType
+	Index     int
+	SandboxID string
+	Attempt   int
+	// Err is the error of the attempt, for retrying and finished events.
+	Err error
+	// Completed and Total count finished tasks and all tasks.
+	Completed int
+	Total     int
+}
+
+type fanOutOptions struct {
+	concurrency   int
+	taskTimeout   time.Duration
+	retries       int
+	backoff       time.Duration
+	progress      func(FanOutEvent)
+	claimTemplate string
+	claimCount    int
+	claimOptions  []SandboxOption
+}
+
+// FanOutOption configures FanOut.
+type FanOutOption func(*fanOutOptions)
+
+// WithFanOutConcurrency sets how many tasks run at once. Default is 10.
+func WithFanOutConcurrency(n int) FanOutOption {
+	return func(opts *fanOutOptions) {
+		opts.concurrency = n
+	}
+}
+
+// WithFanOutTaskTimeout limits each attempt of a task to timeout.
+func WithFanOutTaskTimeout(timeout time.Duration) FanOutOption {
+	return func(opts *fanOutOptions) {
+		opts.taskTimeout = timeout
+	}
+}
+
+// WithFanOutRetries retries a failed task up to retries times, waiting backoff
+// before the first retry and doubling the wait after each one.
+func WithFanOutRetries(retries int, backoff time.Duration) FanOutOption {
+	return func(opts *fanOutOptions) {
+		opts.retries = retries
+		opts.backoff = backoff
+	}
+}
+
+// WithFanOutProgress calls fn as tasks start, retry and finish.
+// Calls are serialized, so fn needs no locking.
+func WithFanOutProgress(fn func(FanOutEvent)) FanOutOption {
+	return func(opts *fanOutOptions) {
+		opts.progress = fn
+	}
+}
+
+// WithFanOutClaim adds count tasks, each running on a sandbox claimed from
+// template when the task starts and deleted when it finishes. A failed claim
+// is retried like a failed task.
+func WithFanOutClaim(template string, count int, opts ...SandboxOption) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.claimTemplate = template
+		o.claimCount = count
+		o.claimOptions = opts
+	}
+}
+
+// FanOut runs fn on each sandbox with bounded concurrency and returns the
+// results in task order. Go methods cannot have type parameters, so FanOut is
+// a function taking the client rather than a Client method.
+//
+// The returned error joins a *FanOutError for each failed task and is nil when
+// every task succeeded. If ctx is done, tasks that have not started fail with
+// ctx.Err().
+func FanOut[T any](ctx context.Context, c *Client, sandboxes []*Sandbox, fn func(context.Context, *Sandbox) (T, error), opts ...FanOutOption) ([]FanOutResult[T], error) {
+	if c == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if fn == nil {
+		return nil, errors.New("fan-out function cannot be nil")
+	}
+	options := fanOutOptions{concurrency: defaultFanOutConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency <= 0 {
+		return nil, errors.New("fan-out concurrency must be positive")
+	}
+	if options.claimCount < 0 {
+		return nil, errors.New("fan-out claim count cannot be negative")
+	}
+	if options.claimCount > 0 && options.claimTemplate == "" {
+		return nil, errors.New("fan-out claim template cannot be empty")
+	}
+	for _, sandbox := range sandboxes {
+		if sandbox == nil {
+			return nil, errors.New("fan-out sandbox cannot be nil")
+		}
+	}
+
+	total := len(sandboxes) + options.claimCount
+	results := make([]FanOutResult[T], total)
+	run := &fanOutRun[T]{client: c, fn: fn, options: options, total: total}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(options.concurrency, total) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var sandbox *Sandbox
+				if i < len(sandboxes) {
+					sandbox = sandboxes[i]
+				}
+				results[i] = run.task(ctx, i, sandbox)
+			}
+		}()
+	}
+	for i := range total {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, &FanOutError{Index: result.Index, SandboxID: result.SandboxID, Err: result.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+type fanOutRun[T any] struct {
+	client  *Client
+	fn      func(context.Context, *Sandbox) (T, error)
+	options fanOutOptions
+	total   int
+
+	mu        sync.Mutex
+	completed int
+}
+
+// task runs the task at index on sandbox, or on a claimed sandbox when
+// sandbox is nil, retrying failed attempts.
+func (r *fanOutRun[T]) task(ctx context.Context, index int, sandbox *Sandbox) FanOutResult[T] {
+	result := FanOutResult[T]{Index: index}
+	if sandbox != nil {
+		result.SandboxID = sandbox.ID
+	}
+	startedAt := time.Now()
+	backoff := r.options.backoff
+	for attempt := 1; attempt <= r.options.retries+1; attempt++ {
+		if err := ctx.Err(); err != nil {
+			if result.Err == nil {
+				result.Err = err
+			}
+			break
+		}
+		if attempt == 1 {
+			r.report(FanOutEvent{Type: FanOutStarted, Index: index, SandboxID: result.SandboxID, Attempt: attempt})
+		}
+		result.Attempts = attempt
+		result.Value, result.Err = r.attempt(ctx, sandbox, &result)
+		if result.Err == nil || attempt > r.options.retries {
+			break
+		}
+		r.report(FanOutEvent{Type: FanOutRetrying, Index: index, SandboxID: result.SandboxID, Attempt: attempt, Err: result.Err})
+		if !sleepContext(ctx, backoff) {
+			result.Err = ctx.Err()
+			break
+		}
+		backoff *= 2
+	}
+	result.Duration = time.Since(startedAt)
+	r.report(FanOutEvent{Type: FanOutFinished, Index: index, SandboxID: result.SandboxID, Attempt: result.Attempts, Err: result.Err})
+	return result
+}
+
+// attempt runs fn once. A nil sandbox is claimed first and deleted afterwards.
+func (r *fanOutRun[T]) attempt(ctx context.Context, sandbox *Sandbox, result *FanOutResult[T]) (T, error) {
+	var zero T
+	if sandbox == nil {
+		claimed, err := r.client.ClaimSandbox(ctx, r.options.claimTemplate, r.options.claimOptions...)
+		if err != nil {
+			return zero, err
+		}
+		result.SandboxID = claimed.ID
+		defer r.cleanup(ctx, claimed)
+		sandbox = claimed
+	}
+	if r.options.taskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.taskTimeout)
+		defer cancel()
+	}
+	return r.fn(ctx, sandbox)
+}
+
+// cleanup deletes a claimed sandbox, even when ctx is done.
+func (r *fanOutRun[T]) cleanup(ctx context.Context, sandbox *Sandbox) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fanOutCleanupTimeout)
+	defer cancel()
+	_, _ = r.client.DeleteSandbox(ctx, sandbox.ID)
+}
+
+func (r *fanOutRun[T]) report(event FanOutEvent) {
+	if r.options.progress == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.Type == FanOutFinished {
+		r.completed++
+	}
+	event.Completed, event.Total = r.completed, r.total
+	r.options.progress(event)
+}
+
+// sleepContext waits for d or until ctx is done, reporting whether d elapsed.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
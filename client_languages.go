@@ -0,0 +1,175 @@
+package sandbox0
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+// replPresets holds built-in REPL configurations keyed by lowercase language name.
+var replPresets = map[string]apispec.REPLConfig{
+	"python": {
+		Name:        "python",
+		DisplayName: apispec.NewOptString("Python"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "python3", Args: []string{"-i", "-u", "-q"}},
+			{Name: "python", Args: []string{"-i", "-u", "-q"}},
+		},
+		Env:   []apispec.REPLEnvVar{{Name: "PYTHONUNBUFFERED", Value: apispec.NewOptString("1")}},
+		Ready: promptTokenReady(">>> "),
+	},
+	"ipython": {
+		Name:        "ipython",
+		DisplayName: apispec.NewOptString("IPython"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "ipython", Args: []string{"--simple-prompt", "--no-banner", "--colors=NoColor"}},
+			{Name: "ipython3", Args: []string{"--simple-prompt", "--no-banner", "--colors=NoColor"}},
+		},
+		Ready: promptTokenReady("In ["),
+	},
+	"node": {
+		Name:        "node",
+		DisplayName: apispec.NewOptString("Node.js"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "node", Args: []string{"--interactive"}},
+		},
+		Env:   []apispec.REPLEnvVar{{Name: "NODE_DISABLE_COLORS", Value: apispec.NewOptString("1")}},
+		Ready: promptTokenReady("> "),
+	},
+	"bash": {
+		Name:        "bash",
+		DisplayName: apispec.NewOptString("Bash"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "bash", Args: []string{"--norc", "--noprofile", "-i"}},
+		},
+		Env: []apispec.REPLEnvVar{
+			{Name: "PS1", Value: apispec.NewOptString("$ ")},
+			{Name: "PS2", Value: apispec.NewOptString("> ")},
+		},
+		Ready: promptTokenReady("$ "),
+	},
+	"ruby": {
+		Name:        "ruby",
+		DisplayName: apispec.NewOptString("Ruby"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "irb", Args: []string{"--simple-prompt", "--nocolorize"}},
+		},
+		Ready: promptTokenReady(">> "),
+	},
+	"r": {
+		Name:        "R",
+		DisplayName: apispec.NewOptString("R"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "R", Args: []string{"--interactive", "--no-save", "--no-restore", "--quiet"}},
+		},
+		Ready: promptTokenReady("> "),
+	},
+	"julia": {
+		Name:        "julia",
+		DisplayName: apispec.NewOptString("Julia"),
+		Candidates: []apispec.ExecCandidate{
+			{Name: "julia", Args: []string{"--banner=no", "--color=no", "-i"}},
+		},
+		Ready: promptTokenReady("julia> "),
+	},
+}
+
+// serverLanguages are the languages the server provides a REPL for. Run
+// leaves their configuration to the server unless one is given with
+// WithREPLConfig or Client.RegisterLanguage.
+var serverLanguages = map[string]bool{
+	"python": true,
+	"node":   true,
+	"bash":   true,
+}
+
+func promptTokenReady(token string) apispec.OptREPLReadyConfig {
+	return apispec.NewOptREPLReadyConfig(apispec.REPLReadyConfig{
+		Mode:  apispec.NewOptREPLReadyMode(apispec.REPLReadyModePromptToken),
+		Token: apispec.NewOptString(token),
+	})
+}
+
+// REPLPreset returns a copy of the built-in REPL configuration for language.
+// Presets exist for python, ipython, node, bash, ruby, R and julia. Run
+// applies them to the languages the server does not provide itself; pass the
+// preset to WithREPLConfig to use it for python, node or bash.
+func REPLPreset(language string) (apispec.REPLConfig, bool) {
+	config, ok := replPresets[strings.ToLower(strings.TrimSpace(language))]
+	if !ok {
+		return apispec.REPLConfig{}, false
+	}
+	return cloneREPLConfig(config), true
+}
+
+// RegisterLanguage makes Sandbox.Run start REPL contexts for name with config.
+// It replaces an earlier registration or built-in preset of the same name.
+// Names are matched case-insensitively.
+func (c *Client) RegisterLanguage(name string, config apispec.REPLConfig) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("language name cannot be empty")
+	}
+	if len(config.Candidates) == 0 {
+		return errors.New("repl config requires at least one candidate")
+	}
+	for _, candidate := range config.Candidates {
+		if strings.TrimSpace(candidate.Name) == "" {
+			return errors.New("repl config candidate name cannot be empty")
+		}
+	}
+	if strings.TrimSpace(config.Name) == "" {
+		config.Name = name
+	}
+
+	c.languagesMu.Lock()
+	defer c.languagesMu.Unlock()
+	if c.languages == nil {
+		c.languages = make(map[string]apispec.REPLConfig)
+	}
+	c.languages[name] = cloneREPLConfig(config)
+	return nil
+}
+
+// languageConfig returns the REPL configuration for language from the
+// client registry, falling back to the built-in presets for languages the
+// server does not provide.
+func (c *Client) languageConfig(language string) (apispec.REPLConfig, bool) {
+	key := strings.ToLower(strings.TrimSpace(language))
+	c.languagesMu.RLock()
+	config, ok := c.languages[key]
+	c.languagesMu.RUnlock()
+	if ok {
+		return cloneREPLConfig(config), true
+	}
+	if serverLanguages[key] {
+		return apispec.REPLConfig{}, false
+	}
+	return REPLPreset(key)
+}
+
+// replPrompts returns the prompt tokens configured by config.
+func replPrompts(config apispec.REPLConfig) []string {
+	var prompts []string
+	if prompt, ok := config.Prompt.Get(); ok {
+		if custom, ok := prompt.CustomPrompt.Get(); ok && custom != "" {
+			prompts = append(prompts, custom)
+		}
+	}
+	if ready, ok := config.Ready.Get(); ok && ready.Mode.Or(apispec.REPLReadyModePromptToken) == apispec.REPLReadyModePromptToken {
+		if token, ok := ready.Token.Get(); ok && token != "" {
+			prompts = append(prompts, token)
+		}
+	}
+	return prompts
+}
+
+func cloneREPLConfig(config apispec.REPLConfig) apispec.REPLConfig {
+	config.Candidates = append([]apispec.ExecCandidate(nil), config.Candidates...)
+	for i := range config.Candidates {
+		config.Candidates[i].Args = append([]string(nil), config.Candidates[i].Args...)
+	}
+	config.Env = append([]apispec.REPLEnvVar(nil), config.Env...)
+	return config
+}
@@ -43,6 +43,38 @@ func WithSandboxHardTTL(ttlSec int32) SandboxOption {
 	}
 }
 
+// WithSandboxEnvVars sets environment variables for created sandboxes.
+// Variables are merged with any previously configured ones.
+func WithSandboxEnvVars(envVars map[string]string) SandboxOption {
+	return func(opts *sandboxOptions) {
+		config := ensureSandboxConfig(opts)
+		merged := apispec.SandboxConfigEnvVars{}
+		if existing, ok := config.EnvVars.Get(); ok {
+			for name, value := range existing {
+				merged[name] = value
+			}
+		}
+		for name, value := range envVars {
+			merged[name] = value
+		}
+		config.EnvVars = apispec.NewOptSandboxConfigEnvVars(merged)
+	}
+}
+
+// WithSandboxExposedPorts sets the ports exposed publicly at claim time.
+func WithSandboxExposedPorts(ports ...ExposedPort) SandboxOption {
+	return func(opts *sandboxOptions) {
+		config := ensureSandboxConfig(opts)
+		config.ExposedPorts = make([]apispec.ExposedPortConfig, len(ports))
+		for i, p := range ports {
+			config.ExposedPorts[i] = apispec.ExposedPortConfig{
+				Port:   p.Port,
+				Resume: p.Resume,
+			}
+		}
+	}
+}
+
 // WithSandboxWebhook configures webhook delivery for sandbox events.
 func WithSandboxWebhook(url, secret string) SandboxOption {
 	return func(opts *sandboxOptions) {
@@ -88,6 +120,7 @@ func WithSandboxAutoResume(enabled bool) SandboxOption {
 }
 
 // ClaimSandbox creates (claims) a sandbox and returns a convenience wrapper.
+// The configuration assembled from opts is validated before any request is sent.
 func (c *Client) ClaimSandbox(ctx context.Context, template string, opts ...SandboxOption) (*Sandbox, error) {
 	options := sandboxOptions{}
 	for _, opt := range opts {
@@ -98,6 +131,9 @@ func (c *Client) ClaimSandbox(ctx context.Context, template string, opts ...Sand
 		Template: apispec.NewOptString(template),
 	}
 	if options.config != nil {
+		if err := ValidateSandboxConfig(*options.config); err != nil {
+			return nil, err
+		}
 		req.Config = apispec.NewOptSandboxConfig(*options.config)
 	}
 
@@ -116,13 +152,15 @@ func (c *Client) ClaimSandbox(ctx context.Context, template string, opts ...Sand
 			clusterID = &value
 		}
 		sandbox := &Sandbox{
-			ID:                data.SandboxID,
-			Template:          data.Template,
-			ClusterID:         clusterID,
-			PodName:           data.PodName,
-			Status:            data.Status,
-			client:            c,
-			replContextByLang: map[string]string{},
+			ID:           data.SandboxID,
+			Template:     data.Template,
+			ClusterID:    clusterID,
+			PodName:      data.PodName,
+			Status:       data.Status,
+			client:       c,
+			replContexts: map[string]string{},
+			replStreams:  map[string]*ContextStream{},
+			jobs:         map[string]*Job{},
 		}
 		return sandbox, nil
 	default:
@@ -148,8 +186,18 @@ func (c *Client) GetSandbox(ctx context.Context, sandboxID string) (*apispec.San
 	}
 }
 
-// UpdateSandbox updates sandbox configuration.
+// UpdateSandbox updates sandbox configuration. The request config is partial:
+// only the fields it sets are changed, and they are checked on their own
+// rather than as a whole configuration. It is sent as given, without
+// diffing, since UpdateSandbox has no previous configuration to compare it
+// with. Use UpdateSandboxConfig, which takes the previous configuration, to
+// validate the complete configuration and send only the fields that changed.
 func (c *Client) UpdateSandbox(ctx context.Context, sandboxID string, request apispec.SandboxUpdateRequest) (*apispec.Sandbox, error) {
+	if config, ok := request.Config.Get(); ok {
+		if err := validateSandboxConfig(config, true); err != nil {
+			return nil, err
+		}
+	}
 	resp, err := c.api.APIV1SandboxesIDPut(ctx, &request, apispec.APIV1SandboxesIDPutParams{ID: sandboxID})
 	if err != nil {
 		return nil, err
@@ -123,3 +123,131 @@ func unexpectedResponseError(res any) *APIError {
 		Message:    "unexpected response",
 	}
 }
+
+// ExitError reports a command that completed with a non-zero exit status.
+type ExitError struct {
+	SandboxID string
+	ContextID string
+	Command   string
+	ExitCode  int
+	// Stderr holds the captured standard error when it was collected by the caller,
+	// such as Sandbox.Cmd or RemoteCmd.Output.
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Command != "" {
+		return fmt.Sprintf("command %q exited with code %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("command exited with code %d", e.ExitCode)
+}
+
+// TraceFrame is one frame of a REPL exception traceback.
+type TraceFrame struct {
+	File     string
+	Line     int
+	Column   int
+	Function string
+	// Code is the source line shown with the frame, when the REPL prints it.
+	Code string
+}
+
+// REPLError is an exception raised by code executed in a REPL context.
+type REPLError struct {
+	// Type is the exception class, such as "ZeroDivisionError" or "TypeError".
+	Type    string
+	Message string
+	// Traceback lists frames outermost first, as Python prints them.
+	// Node stack frames are listed innermost first, as Node prints them.
+	Traceback []TraceFrame
+	// Raw is the exception text as printed by the REPL.
+	Raw string
+}
+
+func (e *REPLError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Message == "" {
+		return e.Type
+	}
+	return e.Type + ": " + e.Message
+}
+
+// RunError is returned by Sandbox.Run with WithRunFailOnError when the
+// executed code raised an exception.
+type RunError struct {
+	SandboxID string
+	ContextID string
+	Language  string
+	Err       *REPLError
+}
+
+func (e *RunError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s execution failed: %v", e.Language, e.Err)
+}
+
+func (e *RunError) Unwrap() error {
+	if e == nil || e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// FanOutError reports a failed FanOut task.
+type FanOutError struct {
+	Index int
+	// SandboxID is empty when the sandbox could not be claimed.
+	SandboxID string
+	Err       error
+}
+
+func (e *FanOutError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.SandboxID == "" {
+		return fmt.Sprintf("fan-out task %d failed: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("fan-out task %d on sandbox %s failed: %v", e.Index, e.SandboxID, e.Err)
+}
+
+func (e *FanOutError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// DecodeError is returned by RunJSON and CmdJSON when the result of the code
+// or command cannot be decoded.
+type DecodeError struct {
+	SandboxID string
+	ContextID string
+	// Data is the JSON that failed to decode. It is empty when no result was
+	// written to the result file or found in the output.
+	Data string
+	// Output is the raw output of the code or command.
+	Output string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("decode JSON result: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
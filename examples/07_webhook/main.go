@@ -17,7 +17,8 @@ func main() {
 
 	webhookURL := os.Getenv("SANDBOX0_WEBHOOK_URL")
 	if webhookURL == "" {
-		// Tip: use https://webhook.site to get a temporary URL for debugging.
+		// Tip: run examples/10_webhook_receiver behind a tunnel, or use
+		// https://webhook.site to get a temporary URL for debugging.
 		log.Fatal("SANDBOX0_WEBHOOK_URL is required")
 	}
 	webhookSecret := os.Getenv("SANDBOX0_WEBHOOK_SECRET")
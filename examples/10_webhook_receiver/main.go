@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/sandbox0webhook"
+)
+
+func main() {
+	addr := os.Getenv("SANDBOX0_WEBHOOK_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	// Must match the secret passed to sandbox0.WithSandboxWebhook.
+	secret := os.Getenv("SANDBOX0_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Fatal("SANDBOX0_WEBHOOK_SECRET is required")
+	}
+
+	handler := sandbox0webhook.NewHandler(secret,
+		sandbox0webhook.WithHandlers(sandbox0webhook.Handlers{
+			ProcessStarted: func(_ context.Context, e *sandbox0webhook.ProcessStartedEvent) error {
+				log.Printf("[%s] process started: context=%s", e.SandboxID, e.ContextID)
+				return nil
+			},
+			ProcessExited: func(_ context.Context, e *sandbox0webhook.ProcessExitedEvent) error {
+				log.Printf("[%s] process exited: context=%s", e.SandboxID, e.ContextID)
+				return nil
+			},
+			ProcessCrashed: func(_ context.Context, e *sandbox0webhook.ProcessCrashedEvent) error {
+				code := -1
+				if e.ExitCode != nil {
+					code = *e.ExitCode
+				}
+				log.Printf("[%s] process crashed: context=%s exit_code=%d", e.SandboxID, e.ContextID, code)
+				return nil
+			},
+			SandboxPaused: func(_ context.Context, e *sandbox0webhook.SandboxPausedEvent) error {
+				log.Printf("[%s] sandbox paused", e.SandboxID)
+				return nil
+			},
+			SandboxResumed: func(_ context.Context, e *sandbox0webhook.SandboxResumedEvent) error {
+				log.Printf("[%s] sandbox resumed", e.SandboxID)
+				return nil
+			},
+			FileModified: func(_ context.Context, e *sandbox0webhook.FileModifiedEvent) error {
+				log.Printf("[%s] file %s: %s", e.SandboxID, e.Event, e.Path)
+				return nil
+			},
+		}),
+		sandbox0webhook.WithErrorLog(func(r *http.Request, err error) {
+			log.Printf("rejected delivery from %s: %v", r.RemoteAddr, err)
+		}),
+	)
+
+	http.Handle("/webhook", handler)
+	log.Printf("listening for Sandbox0 webhooks on %s/webhook", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func main() {
+	ctx := context.Background()
+
+	client, err := sandbox0.NewClient(
+		sandbox0.WithToken(os.Getenv("SANDBOX0_TOKEN")),
+		sandbox0.WithBaseURL(os.Getenv("SANDBOX0_BASE_URL")),
+	)
+	must(err)
+
+	sandbox, err := client.ClaimSandbox(ctx, "default", sandbox0.WithSandboxHardTTL(600))
+	must(err)
+	defer func() {
+		if _, err := client.DeleteSandbox(ctx, sandbox.ID); err != nil {
+			log.Printf("cleanup delete sandbox %s: %v", sandbox.ID, err)
+		}
+	}()
+
+	// Start an interactive shell and attach the local terminal to it.
+	ctxResp, err := sandbox.CreateContext(ctx, apispec.CreateContextRequest{
+		Type: apispec.NewOptProcessType(apispec.ProcessTypeRepl),
+		Repl: apispec.NewOptCreateREPLContextRequest(apispec.CreateREPLContextRequest{
+			Language: apispec.NewOptString("bash"),
+		}),
+	})
+	must(err)
+
+	fmt.Fprintln(os.Stderr, "Attached. Press Ctrl-P Ctrl-Q to detach.")
+	err = sandbox.Attach(ctx, ctxResp.ID, sandbox0.AttachOptions{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		TTY:    true,
+	})
+	if errors.Is(err, sandbox0.ErrDetached) {
+		fmt.Fprintln(os.Stderr, "\r\nDetached.")
+		return
+	}
+	must(err)
+}
+
+func must(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	client, err := sandbox0.NewClient(
+		sandbox0.WithToken(os.Getenv("SANDBOX0_TOKEN")),
+		sandbox0.WithBaseURL(os.Getenv("SANDBOX0_BASE_URL")),
+	)
+	must(err)
+
+	// Claim 5 sandboxes on the fly, run the same script in each, and delete
+	// every sandbox once its task finishes.
+	results, err := sandbox0.FanOut(ctx, client, nil,
+		func(ctx context.Context, sandbox *sandbox0.Sandbox) (string, error) {
+			result, err := sandbox.Cmd(ctx, `/bin/sh -c "uname -n"`)
+			return strings.TrimSpace(result.Stdout), err
+		},
+		sandbox0.WithFanOutClaim("default", 5, sandbox0.WithSandboxHardTTL(300)),
+		sandbox0.WithFanOutConcurrency(3),
+		sandbox0.WithFanOutTaskTimeout(time.Minute),
+		sandbox0.WithFanOutRetries(2, time.Second),
+		sandbox0.WithFanOutProgress(func(event sandbox0.FanOutEvent) {
+			if event.Type == sandbox0.FanOutFinished {
+				log.Printf("%d/%d finished (%s)", event.Completed, event.Total, event.SandboxID)
+			}
+		}),
+	)
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("task %d: error: %v\n", result.Index, result.Err)
+			continue
+		}
+		fmt.Printf("task %d on %s: %s\n", result.Index, result.SandboxID, result.Value)
+	}
+	if err != nil {
+		log.Printf("some tasks failed: %v", err)
+	}
+}
+
+func must(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
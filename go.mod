@@ -8,6 +8,7 @@ require (
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
 	github.com/gorilla/websocket v1.5.1
 	github.com/ogen-go/ogen v1.18.0
+	golang.org/x/term v0.37.0
 )
 
 require (
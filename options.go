@@ -20,6 +20,8 @@ type clientConfig struct {
 	userAgent       string
 	requestEditors  []apispec.RequestEditor
 	responseEditors []apispec.ResponseEditor
+	cancelGrace     time.Duration
+	onCancelError   func(contextID string, err error)
 }
 
 // Option configures a Client.
@@ -105,3 +107,32 @@ func WithResponseEditor(editor apispec.ResponseEditor) Option {
 		return nil
 	}
 }
+
+// WithCancelGracePeriod sets how long a remote process gets to stop after each
+// signal sent when the context of ContextExec, Run or Cmd is cancelled. The
+// process is sent INT, then TERM, then KILL. Default is 3 seconds.
+//
+// A ctx deadline sets the TTL of contexts created by Cmd and RemoteCmd to the
+// time left until the deadline plus three grace periods, one per signal, so
+// the default adds 9 seconds.
+func WithCancelGracePeriod(grace time.Duration) Option {
+	return func(cfg *clientConfig) error {
+		if grace <= 0 {
+			return errors.New("cancel grace period must be positive")
+		}
+		cfg.cancelGrace = grace
+		return nil
+	}
+}
+
+// WithCancelErrorHandler calls fn with the errors of the signal requests sent
+// when the context of ContextExec, Run or Cmd is cancelled. The signals are
+// sent in the background after the call returned, so their errors cannot be
+// returned by it. fn may be called from several goroutines at once. See
+// Client.WaitCancellations.
+func WithCancelErrorHandler(fn func(contextID string, err error)) Option {
+	return func(cfg *clientConfig) error {
+		cfg.onCancelError = fn
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+// Package expect automates interactive programs in Sandbox0 contexts, in the
+// style of Tcl expect: wait for output that matches a pattern, then answer it.
+//
+// Spawn starts a command on a PTY in a new CMD context and attaches to its
+// WebSocket; New wraps a ContextStream that is already open. A Session then
+// waits for output with Expect and ExpectAny and answers with Send, SendLine,
+// SendControl and SendKeys:
+//
+//	session, err := expect.Spawn(ctx, sandbox, []string{"ssh-keygen", "-f", "/tmp/key"})
+//	if err != nil {
+//		return err
+//	}
+//	defer session.Close()
+//	_, err = session.ExpectAny(time.Minute,
+//		expect.Case{Pattern: `(?i)passphrase.*:`, Handle: func(s *expect.Session, _ expect.Match) error {
+//			if err := s.SendLine(""); err != nil {
+//				return err
+//			}
+//			return expect.Continue
+//		}},
+//		expect.Case{Pattern: `key fingerprint`},
+//	)
+//
+// Patterns are Go regular expressions matched against the output with escape
+// sequences and control characters removed (see output.Strip), so colors and
+// cursor movement do not get in the way. Line endings are normalized to LF.
+// The stripped output is also kept as a transcript for logging and debugging.
+package expect
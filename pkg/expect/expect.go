@@ -0,0 +1,380 @@
+package expect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/keys"
+	"github.com/sandbox0-ai/sdk-go/pkg/output"
+)
+
+const (
+	// DefaultTimeout is used by Expect, ExpectAny and ExpectEOF when the
+	// timeout passed to them is not positive.
+	DefaultTimeout = 30 * time.Second
+	// outputLimit bounds the unmatched output and the transcript a Session
+	// keeps; older output is dropped first.
+	outputLimit = 1 << 20
+	// closeTimeout bounds the context deletion made by Close.
+	closeTimeout = 30 * time.Second
+)
+
+// Default PTY size of commands started by Spawn.
+const (
+	defaultRows = 24
+	defaultCols = 80
+)
+
+var (
+	// ErrTimeout is returned when no pattern matched within the timeout.
+	ErrTimeout = errors.New("expect: timed out")
+	// ErrEOF is returned when the output ended before a pattern matched.
+	ErrEOF = errors.New("expect: output ended")
+	// Continue is returned by a Case handler to make ExpectAny wait for the
+	// next match instead of returning, with a fresh timeout.
+	Continue = errors.New("expect: continue")
+)
+
+// Match is output matched by Expect or ExpectAny.
+type Match struct {
+	// Before is the output between the end of the previous match and the
+	// start of this one.
+	Before string
+	Text   string
+	// Groups holds the text of the pattern's submatches, with Groups[0] equal
+	// to Text. Groups that did not participate in the match are empty.
+	Groups []string
+}
+
+// Case is one alternative of ExpectAny.
+type Case struct {
+	Pattern string
+	// Handle, if set, is called with the match. Its error is returned by
+	// ExpectAny, unless it is Continue.
+	Handle func(*Session, Match) error
+}
+
+// Session drives an interactive process over a context stream. Output is
+// read continuously in the background and consumed by matches, so a pattern
+// only matches output that arrived after the previous match. All methods are
+// safe for concurrent use.
+type Session struct {
+	SandboxID string
+	ContextID string
+
+	stream *sandbox0.ContextStream
+	// sandbox is set when the Session created the context, so Close deletes it.
+	sandbox *sandbox0.Sandbox
+
+	mu         sync.Mutex
+	stripper   output.Stripper
+	pending    string
+	transcript strings.Builder
+	changed    chan struct{}
+	done       bool
+	err        error
+}
+
+// Spawn starts argv in a new CMD context on a PTY and returns a Session
+// attached to it. It accepts the options of Sandbox.Exec; the PTY is 24x80
+// unless set with sandbox0.WithCmdPTYSize, and WithCmdWait is ignored.
+// Output the command prints before the Session attaches is replayed by the
+// server, so it can still be matched.
+//
+// The Session is not closed when ctx is done; Close stops the command and
+// deletes the context.
+func Spawn(ctx context.Context, sandbox *sandbox0.Sandbox, argv []string, opts ...sandbox0.CmdOption) (*Session, error) {
+	if sandbox == nil {
+		return nil, errors.New("sandbox cannot be nil")
+	}
+	opts = append([]sandbox0.CmdOption{sandbox0.WithCmdPTYSize(defaultRows, defaultCols)}, opts...)
+	opts = append(opts, sandbox0.WithCmdWait(false))
+	result, err := sandbox.Exec(ctx, argv, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := sandbox.OpenStream(context.WithoutCancel(ctx), result.ContextID)
+	if err != nil {
+		_, _ = sandbox.DeleteContext(context.WithoutCancel(ctx), result.ContextID)
+		return nil, err
+	}
+	session := New(stream)
+	session.sandbox = sandbox
+	return session, nil
+}
+
+// New returns a Session that reads stream, such as one opened on a REPL
+// context. The Session consumes all messages of the stream, so it must not
+// be read elsewhere. Close closes the stream but leaves the context running.
+func New(stream *sandbox0.ContextStream) *Session {
+	session := &Session{
+		SandboxID: stream.SandboxID,
+		ContextID: stream.ContextID,
+		stream:    stream,
+		changed:   make(chan struct{}),
+	}
+	go session.read()
+	return session
+}
+
+// Expect waits until the output matches pattern, a Go regular expression,
+// and returns the match. It returns an error wrapping ErrTimeout if nothing
+// matched within timeout, or ErrEOF if the output ended first.
+func (s *Session) Expect(pattern string, timeout time.Duration) (Match, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Match{}, err
+	}
+	_, match, err := s.expect([]*regexp.Regexp{re}, timeout)
+	return match, err
+}
+
+// ExpectAny waits until the output matches the pattern of one of cases, calls
+// its handler and returns its index. When several patterns match, the one
+// matching earliest in the output wins, then the first in cases. A handler
+// returning Continue makes ExpectAny wait for the next match, which lets a
+// handler answer repeated prompts. Errors are as for Expect.
+func (s *Session) ExpectAny(timeout time.Duration, cases ...Case) (int, error) {
+	if len(cases) == 0 {
+		return -1, errors.New("expect: no cases")
+	}
+	patterns := make([]*regexp.Regexp, len(cases))
+	for i, c := range cases {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return -1, fmt.Errorf("expect: case %d: %w", i, err)
+		}
+		patterns[i] = re
+	}
+	for {
+		index, match, err := s.expect(patterns, timeout)
+		if err != nil {
+			return -1, err
+		}
+		handle := cases[index].Handle
+		if handle == nil {
+			return index, nil
+		}
+		if err := handle(s, match); !errors.Is(err, Continue) {
+			return index, err
+		}
+	}
+}
+
+// ExpectEOF waits until the output ends, such as when the command exits, and
+// returns the output that was not matched. It returns an error wrapping
+// ErrTimeout if the output did not end within timeout.
+func (s *Session) ExpectEOF(timeout time.Duration) (string, error) {
+	timer := time.NewTimer(positive(timeout))
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		if s.done {
+			rest := s.pending
+			s.pending = ""
+			err := s.err
+			s.mu.Unlock()
+			if errors.Is(err, sandbox0.ErrStreamClosed) {
+				err = nil
+			}
+			return rest, err
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", fmt.Errorf("%w after %v waiting for end of output", ErrTimeout, positive(timeout))
+		}
+	}
+}
+
+// Send writes text to the process as typed input.
+func (s *Session) Send(text string) error {
+	_, err := s.stream.SendInput(text)
+	return err
+}
+
+// SendLine writes text followed by a newline, as if typed and entered.
+func (s *Session) SendLine(text string) error {
+	return s.Send(text + "\n")
+}
+
+// SendKeys writes key presses encoded as an xterm would send them, such as
+// keys.Up or keys.Ctrl('d').
+func (s *Session) SendKeys(input ...keys.Input) error {
+	_, err := s.stream.SendKeys(input...)
+	return err
+}
+
+// SendControl sends the control character for c, such as 'c' for Ctrl-C or
+// 'd' for Ctrl-D. c is a letter or one of @ [ \ ] ^ _ ?.
+func (s *Session) SendControl(c rune) error {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	switch {
+	case c == '?':
+		return s.Send("\x7f")
+	case c >= '@' && c <= '_':
+		return s.Send(string(c & 0x1f))
+	default:
+		return fmt.Errorf("expect: no control character for %q", c)
+	}
+}
+
+// Transcript returns the output received so far with escape sequences
+// removed, including matched output, up to the most recent 1 MiB.
+func (s *Session) Transcript() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recentTranscript()
+}
+
+// recentTranscript returns up to outputLimit of the latest transcript. It must
+// be called with s.mu held.
+func (s *Session) recentTranscript() string {
+	text := s.transcript.String()
+	return text[max(0, len(text)-outputLimit):]
+}
+
+// Close closes the stream. For a Session created by Spawn it also deletes
+// the context, which stops the command.
+func (s *Session) Close() error {
+	err := s.stream.Close()
+	if s.sandbox == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if _, deleteErr := s.sandbox.DeleteContext(ctx, s.ContextID); deleteErr != nil {
+		var apiErr *sandbox0.APIError
+		if !errors.As(deleteErr, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			return deleteErr
+		}
+	}
+	return nil
+}
+
+// expect waits until one of patterns matches the pending output, consumes the
+// output up to the end of the match and returns the index of the pattern.
+func (s *Session) expect(patterns []*regexp.Regexp, timeout time.Duration) (int, Match, error) {
+	timeout = positive(timeout)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		if index, match, ok := s.match(patterns); ok {
+			s.mu.Unlock()
+			return index, match, nil
+		}
+		if s.done {
+			err := s.err
+			s.mu.Unlock()
+			if err != nil && !errors.Is(err, sandbox0.ErrStreamClosed) {
+				return -1, Match{}, fmt.Errorf("%w: %w", ErrEOF, err)
+			}
+			return -1, Match{}, ErrEOF
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-timer.C:
+			return -1, Match{}, fmt.Errorf("%w after %v waiting for %s", ErrTimeout, timeout, describe(patterns))
+		}
+	}
+}
+
+// match finds the earliest match of patterns in the pending output and
+// consumes it. It must be called with s.mu held.
+func (s *Session) match(patterns []*regexp.Regexp) (int, Match, bool) {
+	best := -1
+	var loc []int
+	for i, re := range patterns {
+		if l := re.FindStringSubmatchIndex(s.pending); l != nil && (best < 0 || l[0] < loc[0]) {
+			best, loc = i, l
+		}
+	}
+	if best < 0 {
+		return -1, Match{}, false
+	}
+	groups := make([]string, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = s.pending[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	match := Match{Before: s.pending[:loc[0]], Text: groups[0], Groups: groups}
+	s.pending = s.pending[loc[1]:]
+	return best, match, true
+}
+
+// read appends output from the stream until it ends, which the server does
+// once the process exited.
+func (s *Session) read() {
+	var err error
+	for msg, msgErr := range s.stream.Messages() {
+		if msgErr != nil {
+			err = msgErr
+			break
+		}
+		if output, ok := msg.(sandbox0.StreamOutput); ok {
+			s.append(output.Data)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	s.err = err
+	s.notify()
+}
+
+func (s *Session) append(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text := s.stripper.Strip(data)
+	if text == "" {
+		return
+	}
+	s.pending += text
+	if len(s.pending) > outputLimit {
+		s.pending = s.pending[len(s.pending)-outputLimit:]
+	}
+	s.transcript.WriteString(text)
+	// Trim the transcript only once it doubled, so appends stay cheap.
+	if s.transcript.Len() > 2*outputLimit {
+		kept := s.recentTranscript()
+		s.transcript.Reset()
+		s.transcript.WriteString(kept)
+	}
+	s.notify()
+}
+
+// notify wakes goroutines waiting for output. It must be called with s.mu held.
+func (s *Session) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func positive(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTimeout
+	}
+	return timeout
+}
+
+func describe(patterns []*regexp.Regexp) string {
+	quoted := make([]string, len(patterns))
+	for i, re := range patterns {
+		quoted[i] = fmt.Sprintf("%q", re.String())
+	}
+	return strings.Join(quoted, " or ")
+}
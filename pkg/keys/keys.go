@@ -0,0 +1,245 @@
+// Package keys encodes key presses as the byte sequences an xterm sends, for
+// driving terminal programs such as vim, less or shells in a PTY context.
+//
+// An Input is a named Key such as Enter or Up, a Combo of a key or character
+// with modifiers, literal Text, or a bracketed Paste. Encode turns inputs into
+// the string to send with Sandbox.SendKeys, ContextStream.SendKeys or any
+// other context input:
+//
+//	sandbox.SendKeys(ctx, contextID, keys.Escape, keys.Text(":wq"), keys.Enter)
+//	sandbox.SendKeys(ctx, contextID, keys.Ctrl('d'))
+//	sandbox.SendKeys(ctx, contextID, keys.With(keys.ModCtrl|keys.ModShift, keys.Right))
+//
+// Keys are encoded in xterm's normal cursor mode. Programs that switch the
+// terminal to application cursor mode, such as vim and less, accept these
+// sequences too.
+package keys
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Input is something that can be typed into a terminal.
+type Input interface {
+	// Encode returns the bytes a terminal sends for the input.
+	Encode() string
+}
+
+// Encode returns the concatenated encoding of inputs.
+func Encode(inputs ...Input) string {
+	var b strings.Builder
+	for _, input := range inputs {
+		if input != nil {
+			b.WriteString(input.Encode())
+		}
+	}
+	return b.String()
+}
+
+// Key is a named key of a keyboard.
+type Key int
+
+const (
+	Enter Key = iota + 1
+	Tab
+	Backspace
+	Escape
+	Space
+	Up
+	Down
+	Right
+	Left
+	Home
+	End
+	Insert
+	Delete
+	PageUp
+	PageDown
+	F1
+	F2
+	F3
+	F4
+	F5
+	F6
+	F7
+	F8
+	F9
+	F10
+	F11
+	F12
+)
+
+// keySequence describes how xterm encodes a key: plain without modifiers and,
+// for keys with a final byte, CSI number ; modifier final with them.
+type keySequence struct {
+	plain  string
+	number int
+	final  byte
+}
+
+var keySequences = map[Key]keySequence{
+	Enter:     {plain: "\r"},
+	Tab:       {plain: "\t"},
+	Backspace: {plain: "\x7f"},
+	Escape:    {plain: "\x1b"},
+	Space:     {plain: " "},
+	Up:        {plain: "\x1b[A", number: 1, final: 'A'},
+	Down:      {plain: "\x1b[B", number: 1, final: 'B'},
+	Right:     {plain: "\x1b[C", number: 1, final: 'C'},
+	Left:      {plain: "\x1b[D", number: 1, final: 'D'},
+	Home:      {plain: "\x1b[H", number: 1, final: 'H'},
+	End:       {plain: "\x1b[F", number: 1, final: 'F'},
+	Insert:    {plain: "\x1b[2~", number: 2, final: '~'},
+	Delete:    {plain: "\x1b[3~", number: 3, final: '~'},
+	PageUp:    {plain: "\x1b[5~", number: 5, final: '~'},
+	PageDown:  {plain: "\x1b[6~", number: 6, final: '~'},
+	F1:        {plain: "\x1bOP", number: 1, final: 'P'},
+	F2:        {plain: "\x1bOQ", number: 1, final: 'Q'},
+	F3:        {plain: "\x1bOR", number: 1, final: 'R'},
+	F4:        {plain: "\x1bOS", number: 1, final: 'S'},
+	F5:        {plain: "\x1b[15~", number: 15, final: '~'},
+	F6:        {plain: "\x1b[17~", number: 17, final: '~'},
+	F7:        {plain: "\x1b[18~", number: 18, final: '~'},
+	F8:        {plain: "\x1b[19~", number: 19, final: '~'},
+	F9:        {plain: "\x1b[20~", number: 20, final: '~'},
+	F10:       {plain: "\x1b[21~", number: 21, final: '~'},
+	F11:       {plain: "\x1b[23~", number: 23, final: '~'},
+	F12:       {plain: "\x1b[24~", number: 24, final: '~'},
+}
+
+// Encode returns the xterm sequence for k, or "" for an unknown key.
+func (k Key) Encode() string {
+	return keySequences[k].plain
+}
+
+// Modifier is a set of modifier keys, combined with |.
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModAlt
+	ModCtrl
+	ModMeta
+)
+
+// Combo is a key or character pressed with modifiers. Set either Key or Rune.
+type Combo struct {
+	Mod  Modifier
+	Key  Key
+	Rune rune
+}
+
+// With returns key pressed with mod, such as With(ModCtrl, Left).
+func With(mod Modifier, key Key) Combo {
+	return Combo{Mod: mod, Key: key}
+}
+
+// Ctrl returns r pressed with Control, such as Ctrl('c') for an interrupt or
+// Ctrl('d') for end of input.
+func Ctrl(r rune) Combo {
+	return Combo{Mod: ModCtrl, Rune: r}
+}
+
+// Alt returns r pressed with Alt, which xterm sends as ESC followed by r.
+func Alt(r rune) Combo {
+	return Combo{Mod: ModAlt, Rune: r}
+}
+
+// Encode returns the xterm sequence for c. Cursor, editing and function keys
+// carry their modifiers in the sequence; other keys and characters are sent
+// with Control applied as a control character where one exists and Alt or
+// Meta as an ESC prefix. Shift applies to letters and Tab.
+func (c Combo) Encode() string {
+	if c.Key == 0 {
+		return c.encodeRune()
+	}
+	seq, ok := keySequences[c.Key]
+	if !ok {
+		return ""
+	}
+	if c.Mod == 0 {
+		return seq.plain
+	}
+	if seq.final != 0 {
+		param := 1 + int(c.Mod)
+		return "\x1b[" + strconv.Itoa(seq.number) + ";" + strconv.Itoa(param) + string(seq.final)
+	}
+
+	text := seq.plain
+	switch {
+	case c.Key == Tab && c.Mod&ModShift != 0:
+		text = "\x1b[Z"
+	case c.Key == Space && c.Mod&ModCtrl != 0:
+		text = "\x00"
+	case c.Key == Backspace && c.Mod&ModCtrl != 0:
+		text = "\b"
+	}
+	if c.Mod&(ModAlt|ModMeta) != 0 {
+		text = "\x1b" + text
+	}
+	return text
+}
+
+func (c Combo) encodeRune() string {
+	r := c.Rune
+	if c.Mod&ModShift != 0 {
+		r = unicode.ToUpper(r)
+	}
+	text := string(r)
+	if c.Mod&ModCtrl != 0 {
+		if control, ok := controlChar(r); ok {
+			text = string(control)
+		}
+	}
+	if c.Mod&(ModAlt|ModMeta) != 0 {
+		text = "\x1b" + text
+	}
+	return text
+}
+
+// controlChar returns the control character typed with Ctrl and r.
+func controlChar(r rune) (rune, bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return r - 'a' + 1, true
+	case r >= '@' && r <= '_':
+		return r - '@', true
+	case r == ' ' || r == '2':
+		return 0, true
+	case r == '?':
+		return 0x7f, true
+	default:
+		return 0, false
+	}
+}
+
+// Text is literal text typed as is. Control characters in it are sent
+// unchanged, so a newline submits a line in most programs.
+type Text string
+
+// Encode returns t unchanged.
+func (t Text) Encode() string {
+	return string(t)
+}
+
+// Paste is text pasted with bracketed paste, so programs that enable it, such
+// as shells and editors, insert it literally instead of interpreting keys in
+// it. Programs that do not enable it see the bracket sequences as input.
+type Paste string
+
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// Encode returns p between the bracketed paste markers. End markers inside p
+// are removed so the paste cannot end early.
+func (p Paste) Encode() string {
+	text := string(p)
+	for strings.Contains(text, pasteEnd) {
+		text = strings.ReplaceAll(text, pasteEnd, "")
+	}
+	return pasteStart + text + pasteEnd
+}
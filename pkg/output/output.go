@@ -0,0 +1,91 @@
+// Package output turns raw PTY output from sandbox contexts into plain text.
+//
+// Screen is a VT100/xterm screen model: it applies carriage returns, cursor
+// movement and erase sequences so progress bars and redrawn lines collapse to
+// what a terminal would finally show. Render and Lines use a Screen without
+// line wrapping to extract that text together with its scrollback.
+//
+// Strip is a cheaper alternative that only removes escape sequences and
+// control characters, keeping every overwritten fragment. Stripper does the
+// same for output that arrives in chunks.
+package output
+
+import "strings"
+
+// Render returns the text a terminal would display after receiving raw,
+// including lines scrolled off the screen. Lines are not wrapped.
+func Render(raw string) string {
+	screen := NewScreen(0, DefaultRows)
+	_, _ = screen.WriteString(raw)
+	return screen.String()
+}
+
+// Lines returns the rendered lines of raw, like Render split on newlines.
+func Lines(raw string) []string {
+	screen := NewScreen(0, DefaultRows)
+	_, _ = screen.WriteString(raw)
+	return screen.Lines()
+}
+
+// Strip removes ANSI escape sequences and control characters from raw.
+// CRLF becomes LF and a lone CR becomes LF, so overwritten text stays visible.
+// Tabs are kept.
+func Strip(raw string) string {
+	var stripper stripper
+	stripper.buf.Grow(len(raw))
+	var p parser
+	p.feed(&stripper, []byte(raw))
+	return stripper.buf.String()
+}
+
+// Stripper is an incremental Strip for output that arrives in chunks, such
+// as messages from a context stream. Escape sequences and UTF-8 characters may
+// be split across chunks. The zero value is ready to use.
+type Stripper struct {
+	parser   parser
+	stripper stripper
+}
+
+// Strip returns chunk with escape sequences and control characters removed.
+// The concatenated results equal Strip of the concatenated chunks, except that
+// a CR at the end of a chunk is held back until the next chunk shows whether
+// it starts a CRLF.
+func (s *Stripper) Strip(chunk string) string {
+	s.stripper.buf.Reset()
+	s.parser.feed(&s.stripper, []byte(chunk))
+	return s.stripper.buf.String()
+}
+
+type stripper struct {
+	buf strings.Builder
+	cr  bool
+}
+
+func (s *stripper) flushCR() {
+	if s.cr {
+		s.buf.WriteByte('\n')
+		s.cr = false
+	}
+}
+
+func (s *stripper) print(r rune) {
+	s.flushCR()
+	s.buf.WriteRune(r)
+}
+
+func (s *stripper) execute(b byte) {
+	switch b {
+	case '\r':
+		s.cr = true
+	case '\n':
+		s.cr = false
+		s.buf.WriteByte('\n')
+	case '\t':
+		s.flushCR()
+		s.buf.WriteByte('\t')
+	}
+}
+
+func (s *stripper) csi(byte, []int, byte) {}
+
+func (s *stripper) esc(byte, byte) {}
@@ -0,0 +1,149 @@
+package output
+
+import "unicode/utf8"
+
+// handler receives actions decoded from a terminal byte stream.
+type handler interface {
+	print(r rune)
+	execute(b byte)
+	csi(private byte, params []int, final byte)
+	esc(intermediate, final byte)
+}
+
+type parserState int
+
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateString
+	stateStringEscape
+)
+
+// parser decodes a VT100/xterm byte stream into handler actions.
+// It keeps its state between calls, so sequences may be split across writes.
+type parser struct {
+	state        parserState
+	private      byte
+	params       []int
+	param        int
+	hasParam     bool
+	intermediate byte
+	// stringBell reports whether BEL terminates the current control string (OSC).
+	stringBell bool
+	partial    []byte
+}
+
+func (p *parser) feed(h handler, data []byte) {
+	if len(p.partial) > 0 {
+		data = append(p.partial, data...)
+		p.partial = nil
+	}
+	for i := 0; i < len(data); {
+		b := data[i]
+		if p.state == stateGround && b >= 0x80 {
+			if !utf8.FullRune(data[i:]) {
+				p.partial = append([]byte(nil), data[i:]...)
+				return
+			}
+			r, size := utf8.DecodeRune(data[i:])
+			h.print(r)
+			i += size
+			continue
+		}
+		p.step(h, b)
+		i++
+	}
+}
+
+func (p *parser) step(h handler, b byte) {
+	switch p.state {
+	case stateGround:
+		switch {
+		case b == 0x1b:
+			p.state = stateEscape
+		case b < 0x20 || b == 0x7f:
+			h.execute(b)
+		default:
+			h.print(rune(b))
+		}
+	case stateEscape:
+		switch {
+		case b == '[':
+			p.state = stateCSI
+			p.private = 0
+			p.params = p.params[:0]
+			p.param = 0
+			p.hasParam = false
+		case b == ']':
+			p.state = stateString
+			p.stringBell = true
+		case b == 'P' || b == 'X' || b == '^' || b == '_':
+			p.state = stateString
+			p.stringBell = false
+		case b >= 0x20 && b <= 0x2f:
+			p.intermediate = b
+			p.state = stateEscapeIntermediate
+		case b == 0x1b:
+		case b < 0x20:
+			h.execute(b)
+		default:
+			p.state = stateGround
+			h.esc(0, b)
+		}
+	case stateEscapeIntermediate:
+		switch {
+		case b >= 0x20 && b <= 0x2f:
+		case b < 0x20:
+			h.execute(b)
+		default:
+			p.state = stateGround
+			h.esc(p.intermediate, b)
+		}
+	case stateCSI:
+		switch {
+		case b >= '0' && b <= '9':
+			p.param = p.param*10 + int(b-'0')
+			if p.param > 9999 {
+				p.param = 9999
+			}
+			p.hasParam = true
+		case b == ';' || b == ':':
+			p.pushParam()
+		case b >= '<' && b <= '?':
+			p.private = b
+		case b >= 0x20 && b <= 0x2f:
+			// Intermediate bytes are accepted and ignored.
+		case b >= 0x40 && b <= 0x7e:
+			if p.hasParam || len(p.params) > 0 {
+				p.pushParam()
+			}
+			p.state = stateGround
+			h.csi(p.private, p.params, b)
+		case b == 0x1b:
+			p.state = stateEscape
+		case b < 0x20:
+			h.execute(b)
+		}
+	case stateString:
+		switch {
+		case b == 0x07 && p.stringBell:
+			p.state = stateGround
+		case b == 0x1b:
+			p.state = stateStringEscape
+		}
+	case stateStringEscape:
+		if b == '\\' {
+			p.state = stateGround
+		} else {
+			p.state = stateString
+		}
+	}
+}
+
+func (p *parser) pushParam() {
+	p.params = append(p.params, p.param)
+	p.param = 0
+	p.hasParam = false
+}
@@ -0,0 +1,375 @@
+package output
+
+import "strings"
+
+// DefaultRows is the screen height used when none is given.
+const DefaultRows = 24
+
+// Screen is a VT100/xterm screen model. Writing raw PTY output to it applies
+// carriage returns, cursor movement and erase sequences, so the screen holds
+// the text a terminal would finally display. Lines scrolled off the top are
+// kept as scrollback. Colors and other attributes are discarded.
+//
+// A Screen is not safe for concurrent use.
+type Screen struct {
+	cols, rows int
+	grid       [][]rune
+	scrollback []string
+	row, col   int
+	// wrapNext defers wrapping until the next printed rune, like xterm.
+	wrapNext bool
+	savedRow int
+	savedCol int
+	top      int
+	bottom   int
+	parser   parser
+}
+
+// NewScreen returns a screen with the given size. Cols <= 0 disables line
+// wrapping, which suits text extraction; rows <= 0 uses DefaultRows.
+func NewScreen(cols, rows int) *Screen {
+	if rows <= 0 {
+		rows = DefaultRows
+	}
+	s := &Screen{cols: cols, rows: rows}
+	s.reset()
+	return s
+}
+
+func (s *Screen) reset() {
+	s.grid = make([][]rune, s.rows)
+	s.scrollback = nil
+	s.row, s.col = 0, 0
+	s.wrapNext = false
+	s.savedRow, s.savedCol = 0, 0
+	s.top, s.bottom = 0, s.rows-1
+}
+
+// Write applies raw terminal output to the screen. It never fails.
+func (s *Screen) Write(p []byte) (int, error) {
+	s.parser.feed(s, p)
+	return len(p), nil
+}
+
+// WriteString is like Write but takes a string.
+func (s *Screen) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// Cursor returns the zero-based cursor position on the visible screen.
+func (s *Screen) Cursor() (row, col int) {
+	return s.row, s.col
+}
+
+// Display returns the visible rows with trailing spaces removed.
+func (s *Screen) Display() []string {
+	lines := make([]string, len(s.grid))
+	for i, line := range s.grid {
+		lines[i] = renderLine(line)
+	}
+	return lines
+}
+
+// Scrollback returns the lines that scrolled off the top of the screen, oldest first.
+func (s *Screen) Scrollback() []string {
+	return append([]string(nil), s.scrollback...)
+}
+
+// Lines returns the scrollback followed by the visible rows, with trailing
+// blank rows removed.
+func (s *Screen) Lines() []string {
+	lines := append(s.Scrollback(), s.Display()...)
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// String returns Lines joined by newlines.
+func (s *Screen) String() string {
+	lines := s.Lines()
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderLine(line []rune) string {
+	out := make([]rune, len(line))
+	for i, r := range line {
+		if r == 0 {
+			r = ' '
+		}
+		out[i] = r
+	}
+	return strings.TrimRight(string(out), " ")
+}
+
+func (s *Screen) print(r rune) {
+	if s.wrapNext {
+		s.wrapNext = false
+		s.col = 0
+		s.lineFeed()
+	}
+	line := s.grid[s.row]
+	for len(line) <= s.col {
+		line = append(line, 0)
+	}
+	line[s.col] = r
+	s.grid[s.row] = line
+	if s.cols > 0 && s.col == s.cols-1 {
+		s.wrapNext = true
+		return
+	}
+	s.col++
+}
+
+func (s *Screen) execute(b byte) {
+	switch b {
+	case '\r':
+		s.col = 0
+		s.wrapNext = false
+	case '\n', '\v', '\f':
+		// PTY output normally arrives as CRLF; a bare LF is treated as a
+		// newline so output written without a PTY renders the same way.
+		s.col = 0
+		s.wrapNext = false
+		s.lineFeed()
+	case '\b':
+		if s.col > 0 {
+			s.col--
+		}
+		s.wrapNext = false
+	case '\t':
+		s.col = (s.col/8 + 1) * 8
+		if s.cols > 0 && s.col >= s.cols {
+			s.col = s.cols - 1
+		}
+	}
+}
+
+func (s *Screen) lineFeed() {
+	if s.row == s.bottom {
+		s.scrollUp(1)
+		return
+	}
+	if s.row < s.rows-1 {
+		s.row++
+	}
+}
+
+func (s *Screen) reverseIndex() {
+	if s.row == s.top {
+		s.scrollDown(1)
+		return
+	}
+	if s.row > 0 {
+		s.row--
+	}
+}
+
+// scrollUp moves the scroll region up by n lines. Lines leaving the top of
+// a region that starts at the first row become scrollback.
+func (s *Screen) scrollUp(n int) {
+	for range min(n, s.bottom-s.top+1) {
+		if s.top == 0 {
+			s.scrollback = append(s.scrollback, renderLine(s.grid[0]))
+		}
+		copy(s.grid[s.top:s.bottom], s.grid[s.top+1:s.bottom+1])
+		s.grid[s.bottom] = nil
+	}
+}
+
+func (s *Screen) scrollDown(n int) {
+	for range min(n, s.bottom-s.top+1) {
+		copy(s.grid[s.top+1:s.bottom+1], s.grid[s.top:s.bottom])
+		s.grid[s.top] = nil
+	}
+}
+
+func (s *Screen) moveTo(row, col int) {
+	s.row = clamp(row, 0, s.rows-1)
+	s.col = max(col, 0)
+	if s.cols > 0 && s.col > s.cols-1 {
+		s.col = s.cols - 1
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) eraseLine(mode int) {
+	line := s.grid[s.row]
+	switch mode {
+	case 0:
+		if s.col < len(line) {
+			s.grid[s.row] = line[:s.col]
+		}
+	case 1:
+		for i := 0; i <= s.col && i < len(line); i++ {
+			line[i] = 0
+		}
+	case 2:
+		s.grid[s.row] = nil
+	}
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for i := s.row + 1; i < s.rows; i++ {
+			s.grid[i] = nil
+		}
+	case 1:
+		s.eraseLine(1)
+		for i := 0; i < s.row; i++ {
+			s.grid[i] = nil
+		}
+	case 2:
+		for i := range s.grid {
+			s.grid[i] = nil
+		}
+	case 3:
+		for i := range s.grid {
+			s.grid[i] = nil
+		}
+		s.scrollback = nil
+	}
+}
+
+func (s *Screen) deleteChars(n int) {
+	line := s.grid[s.row]
+	if s.col >= len(line) {
+		return
+	}
+	n = min(n, len(line)-s.col)
+	s.grid[s.row] = append(line[:s.col], line[s.col+n:]...)
+}
+
+func (s *Screen) insertChars(n int) {
+	line := s.grid[s.row]
+	if s.col >= len(line) {
+		return
+	}
+	blank := make([]rune, n)
+	line = append(line[:s.col], append(blank, line[s.col:]...)...)
+	if s.cols > 0 && len(line) > s.cols {
+		line = line[:s.cols]
+	}
+	s.grid[s.row] = line
+}
+
+func (s *Screen) eraseChars(n int) {
+	line := s.grid[s.row]
+	for i := s.col; i < s.col+n && i < len(line); i++ {
+		line[i] = 0
+	}
+}
+
+func (s *Screen) insertLines(n int) {
+	if s.row < s.top || s.row > s.bottom {
+		return
+	}
+	top := s.top
+	s.top = s.row
+	s.scrollDown(n)
+	s.top = top
+}
+
+func (s *Screen) deleteLines(n int) {
+	if s.row < s.top || s.row > s.bottom {
+		return
+	}
+	n = min(n, s.bottom-s.row+1)
+	for range n {
+		copy(s.grid[s.row:s.bottom], s.grid[s.row+1:s.bottom+1])
+		s.grid[s.bottom] = nil
+	}
+}
+
+func (s *Screen) csi(private byte, params []int, final byte) {
+	arg := func(i, def int) int {
+		if i < len(params) && params[i] > 0 {
+			return params[i]
+		}
+		return def
+	}
+	if private != 0 {
+		// Private modes (cursor visibility, alternate screen, bracketed paste)
+		// do not change the text content.
+		return
+	}
+	switch final {
+	case 'A':
+		s.moveTo(s.row-arg(0, 1), s.col)
+	case 'B', 'e':
+		s.moveTo(s.row+arg(0, 1), s.col)
+	case 'C', 'a':
+		s.moveTo(s.row, s.col+arg(0, 1))
+	case 'D':
+		s.moveTo(s.row, s.col-arg(0, 1))
+	case 'E':
+		s.moveTo(s.row+arg(0, 1), 0)
+	case 'F':
+		s.moveTo(s.row-arg(0, 1), 0)
+	case 'G', '`':
+		s.moveTo(s.row, arg(0, 1)-1)
+	case 'H', 'f':
+		s.moveTo(arg(0, 1)-1, arg(1, 1)-1)
+	case 'd':
+		s.moveTo(arg(0, 1)-1, s.col)
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(arg(0, 0))
+	case 'L':
+		s.insertLines(arg(0, 1))
+	case 'M':
+		s.deleteLines(arg(0, 1))
+	case 'P':
+		s.deleteChars(arg(0, 1))
+	case '@':
+		s.insertChars(arg(0, 1))
+	case 'X':
+		s.eraseChars(arg(0, 1))
+	case 'S':
+		s.scrollUp(arg(0, 1))
+	case 'T':
+		s.scrollDown(arg(0, 1))
+	case 'r':
+		top, bottom := arg(0, 1)-1, arg(1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, 0)
+		}
+	case 's':
+		s.savedRow, s.savedCol = s.row, s.col
+	case 'u':
+		s.moveTo(s.savedRow, s.savedCol)
+	}
+}
+
+func (s *Screen) esc(intermediate, final byte) {
+	if intermediate != 0 {
+		// Character set designations such as ESC ( B.
+		return
+	}
+	switch final {
+	case '7':
+		s.savedRow, s.savedCol = s.row, s.col
+	case '8':
+		s.moveTo(s.savedRow, s.savedCol)
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.col = 0
+		s.lineFeed()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset()
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
@@ -0,0 +1,91 @@
+package sandbox0webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSubscriberFull is returned by Publish when a subscriber's buffer was full
+// and the event was dropped for it.
+var ErrSubscriberFull = errors.New("sandbox0webhook: subscriber buffer full")
+
+// Broker fans accepted events out to per-sandbox subscribers.
+// Attach it to a Handler with WithBroker.
+type Broker struct {
+	mu   sync.RWMutex
+	subs map[string]map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch   chan Event
+	done chan struct{}
+}
+
+// NewBroker creates an empty broker.
+func NewBroker() *Broker {
+	return &Broker{subs: map[string]map[*subscription]struct{}{}}
+}
+
+// Subscribe returns a channel receiving events for sandboxID, or for every
+// sandbox when sandboxID is empty. Events that arrive while the buffer is full
+// are dropped for this subscriber. The returned function cancels the
+// subscription; the channel is not closed, so stop receiving after calling it.
+func (b *Broker) Subscribe(sandboxID string, buffer int) (<-chan Event, func()) {
+	sub := &subscription{ch: make(chan Event, buffer), done: make(chan struct{})}
+	b.mu.Lock()
+	if b.subs[sandboxID] == nil {
+		b.subs[sandboxID] = map[*subscription]struct{}{}
+	}
+	b.subs[sandboxID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[sandboxID], sub)
+			if len(b.subs[sandboxID]) == 0 {
+				delete(b.subs, sandboxID)
+			}
+			b.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// Publish delivers event to every matching subscriber without blocking.
+// Subscribers whose buffer is full miss the event; Publish still offers it to
+// the others and then returns an error wrapping ErrSubscriberFull.
+func (b *Broker) Publish(ctx context.Context, event Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sandboxID := event.Metadata().SandboxID
+	b.mu.RLock()
+	var targets []*subscription
+	for sub := range b.subs[sandboxID] {
+		targets = append(targets, sub)
+	}
+	if sandboxID != "" {
+		for sub := range b.subs[""] {
+			targets = append(targets, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	dropped := 0
+	for _, sub := range targets {
+		select {
+		case sub.ch <- event:
+		case <-sub.done:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("%w: event %s dropped for %d subscriber(s)", ErrSubscriberFull, event.Metadata().ID, dropped)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package sandbox0webhook receives, verifies and simulates Sandbox0 webhook deliveries.
+//
+// A sandbox claimed with sandbox0.WithSandboxWebhook(url, secret) POSTs JSON
+// events to url. Handler verifies the signature computed with the shared
+// secret, rejects stale or replayed deliveries, decodes the payload into
+// a typed Event and dispatches it to callbacks, a channel or a Broker that
+// fans events out per sandbox.
+//
+// The Sandbox0 API spec only says that the webhook secret signs payloads; it
+// does not define headers, signature format or payload envelope. This package
+// assumes them: DefaultScheme expects X-Sandbox0-Timestamp with the send time
+// in Unix seconds and X-Sandbox0-Signature with "sha256=" and the hex
+// HMAC-SHA256 of "<timestamp>.<body>", and Payload expects a JSON envelope with
+// event_id, event_type, sandbox_id, timestamp and data. Use WithScheme when
+// the server signs differently. A Handler without a secret rejects deliveries
+// unless WithoutVerification is set.
+//
+// An optional EventStore (MemoryStore or the JSON Lines FileStore) persists
+// deliveries, deduplicates them by event ID and lets consumers that were
+// offline catch up with Replay from a cursor.
+//
+// Simulator sends signed events to a local receiver, with optional duplicate,
+// reordering and bad-signature faults, so receivers can be tested without a
+// running sandbox.
+package sandbox0webhook
@@ -0,0 +1,174 @@
+package sandbox0webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventType identifies a webhook event.
+type EventType string
+
+// Event types delivered by Sandbox0.
+const (
+	EventSandboxClaimed EventType = "sandbox.claimed"
+	EventProcessStarted EventType = "process.started"
+	EventProcessExited  EventType = "process.exited"
+	EventProcessCrashed EventType = "process.crashed"
+	EventSandboxPaused  EventType = "sandbox.paused"
+	EventSandboxResumed EventType = "sandbox.resumed"
+	EventFileModified   EventType = "file.modified"
+)
+
+// Payload is the JSON envelope assumed for a webhook delivery; the API spec
+// does not define it.
+type Payload struct {
+	EventID   string          `json:"event_id"`
+	EventType EventType       `json:"event_type"`
+	SandboxID string          `json:"sandbox_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Meta holds the fields common to every event.
+type Meta struct {
+	ID        string
+	Type      EventType
+	SandboxID string
+	Timestamp time.Time
+}
+
+// Event is implemented by all typed webhook events.
+type Event interface {
+	Metadata() Meta
+}
+
+// Metadata returns the common event fields.
+func (m Meta) Metadata() Meta {
+	return m
+}
+
+// ProcessData describes the process an event refers to.
+type ProcessData struct {
+	ContextID   string `json:"context_id"`
+	ProcessType string `json:"type,omitempty"`
+	Language    string `json:"language,omitempty"`
+	PID         int    `json:"pid,omitempty"`
+	ExitCode    *int   `json:"exit_code,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ProcessStartedEvent is sent when a context process starts.
+type ProcessStartedEvent struct {
+	Meta
+	ProcessData
+}
+
+// ProcessExitedEvent is sent when a context process exits with code zero.
+type ProcessExitedEvent struct {
+	Meta
+	ProcessData
+}
+
+// ProcessCrashedEvent is sent when a context process exits abnormally.
+type ProcessCrashedEvent struct {
+	Meta
+	ProcessData
+}
+
+// SandboxClaimedEvent is sent when a sandbox is claimed.
+type SandboxClaimedEvent struct {
+	Meta
+	SandboxData
+}
+
+// SandboxData describes the sandbox an event refers to.
+type SandboxData struct {
+	Template string `json:"template,omitempty"`
+}
+
+// SandboxPausedEvent is sent when a sandbox is paused.
+type SandboxPausedEvent struct {
+	Meta
+}
+
+// SandboxResumedEvent is sent when a sandbox is resumed.
+type SandboxResumedEvent struct {
+	Meta
+}
+
+// FileData describes a file change under the configured watch_dir.
+type FileData struct {
+	Path     string `json:"path"`
+	Event    string `json:"event"`
+	WatchDir string `json:"watch_dir,omitempty"`
+}
+
+// FileModifiedEvent is sent for file changes under the configured watch_dir.
+type FileModifiedEvent struct {
+	Meta
+	FileData
+}
+
+// UnknownEvent carries events of a type this package does not know about.
+type UnknownEvent struct {
+	Meta
+	Data json.RawMessage
+}
+
+// ParseEvent decodes a webhook body into a typed Event.
+// Unrecognized event types are returned as *UnknownEvent.
+func ParseEvent(body []byte) (Event, error) {
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("sandbox0webhook: decode payload: %w", err)
+	}
+	return payload.Event()
+}
+
+// Event converts the payload into its typed event.
+func (p Payload) Event() (Event, error) {
+	if p.EventType == "" {
+		return nil, errors.New("sandbox0webhook: payload has no event_type")
+	}
+	meta := Meta{
+		ID:        p.EventID,
+		Type:      p.EventType,
+		SandboxID: p.SandboxID,
+		Timestamp: p.Timestamp,
+	}
+	var (
+		event Event
+		data  any
+	)
+	switch p.EventType {
+	case EventProcessStarted:
+		e := &ProcessStartedEvent{Meta: meta}
+		event, data = e, &e.ProcessData
+	case EventProcessExited:
+		e := &ProcessExitedEvent{Meta: meta}
+		event, data = e, &e.ProcessData
+	case EventProcessCrashed:
+		e := &ProcessCrashedEvent{Meta: meta}
+		event, data = e, &e.ProcessData
+	case EventSandboxClaimed:
+		e := &SandboxClaimedEvent{Meta: meta}
+		event, data = e, &e.SandboxData
+	case EventSandboxPaused:
+		event = &SandboxPausedEvent{Meta: meta}
+	case EventSandboxResumed:
+		event = &SandboxResumedEvent{Meta: meta}
+	case EventFileModified:
+		e := &FileModifiedEvent{Meta: meta}
+		event, data = e, &e.FileData
+	default:
+		return &UnknownEvent{Meta: meta, Data: p.Data}, nil
+	}
+	if data != nil && len(p.Data) > 0 && string(p.Data) != "null" {
+		if err := json.Unmarshal(p.Data, data); err != nil {
+			return nil, fmt.Errorf("sandbox0webhook: decode %s data: %w", p.EventType, err)
+		}
+	}
+	return event, nil
+}
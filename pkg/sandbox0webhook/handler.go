@@ -0,0 +1,344 @@
+package sandbox0webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMaxBodyBytes = 1 << 20
+
+// Handlers holds typed event callbacks. Nil callbacks are skipped.
+// A callback error makes the receiver answer 500 so the delivery is retried.
+type Handlers struct {
+	SandboxClaimed func(context.Context, *SandboxClaimedEvent) error
+	ProcessStarted func(context.Context, *ProcessStartedEvent) error
+	ProcessExited  func(context.Context, *ProcessExitedEvent) error
+	ProcessCrashed func(context.Context, *ProcessCrashedEvent) error
+	SandboxPaused  func(context.Context, *SandboxPausedEvent) error
+	SandboxResumed func(context.Context, *SandboxResumedEvent) error
+	FileModified   func(context.Context, *FileModifiedEvent) error
+	Unknown        func(context.Context, *UnknownEvent) error
+}
+
+// Dispatch invokes the callback matching the event type.
+func (hs Handlers) Dispatch(ctx context.Context, event Event) error {
+	switch e := event.(type) {
+	case *SandboxClaimedEvent:
+		if hs.SandboxClaimed != nil {
+			return hs.SandboxClaimed(ctx, e)
+		}
+	case *ProcessStartedEvent:
+		if hs.ProcessStarted != nil {
+			return hs.ProcessStarted(ctx, e)
+		}
+	case *ProcessExitedEvent:
+		if hs.ProcessExited != nil {
+			return hs.ProcessExited(ctx, e)
+		}
+	case *ProcessCrashedEvent:
+		if hs.ProcessCrashed != nil {
+			return hs.ProcessCrashed(ctx, e)
+		}
+	case *SandboxPausedEvent:
+		if hs.SandboxPaused != nil {
+			return hs.SandboxPaused(ctx, e)
+		}
+	case *SandboxResumedEvent:
+		if hs.SandboxResumed != nil {
+			return hs.SandboxResumed(ctx, e)
+		}
+	case *FileModifiedEvent:
+		if hs.FileModified != nil {
+			return hs.FileModified(ctx, e)
+		}
+	case *UnknownEvent:
+		if hs.Unknown != nil {
+			return hs.Unknown(ctx, e)
+		}
+	}
+	return nil
+}
+
+// Handler is an http.Handler that verifies and dispatches webhook deliveries.
+type Handler struct {
+	secret       string
+	unverified   bool
+	scheme       Scheme
+	tolerance    time.Duration
+	maxBodyBytes int64
+	handlers     Handlers
+	events       chan<- Event
+	now          func() time.Time
+	errorLog     func(*http.Request, error)
+	replay       *replayCache
+	store        EventStore
+	broker       *Broker
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithTolerance sets the accepted clock skew for signed timestamps.
+// Deliveries outside the window are rejected. Default is DefaultTolerance.
+func WithTolerance(tolerance time.Duration) Option {
+	return func(h *Handler) {
+		h.tolerance = tolerance
+	}
+}
+
+// WithScheme verifies deliveries with scheme instead of DefaultScheme.
+func WithScheme(scheme Scheme) Option {
+	return func(h *Handler) {
+		h.scheme = scheme
+	}
+}
+
+// WithoutVerification accepts deliveries without checking their signature.
+// It is required when the handler is created with an empty secret; use it only
+// for webhooks configured without a secret.
+func WithoutVerification() Option {
+	return func(h *Handler) {
+		h.unverified = true
+	}
+}
+
+// WithHandlers sets the typed event callbacks.
+func WithHandlers(handlers Handlers) Option {
+	return func(h *Handler) {
+		h.handlers = handlers
+	}
+}
+
+// WithEventChannel delivers every accepted event to ch after the callbacks run.
+// The request blocks until the event is received or the request is cancelled.
+func WithEventChannel(ch chan<- Event) Option {
+	return func(h *Handler) {
+		h.events = ch
+	}
+}
+
+// WithBroker publishes every accepted event to broker after the callbacks run.
+// Events dropped for full subscribers are reported through WithErrorLog; the
+// delivery is still acknowledged so other subscribers do not see it twice.
+func WithBroker(broker *Broker) Option {
+	return func(h *Handler) {
+		h.broker = broker
+	}
+}
+
+// WithMaxBodyBytes limits the accepted payload size. Default is 1 MiB.
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodyBytes = n
+	}
+}
+
+// WithClock overrides the time source used for timestamp checks.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+// WithErrorLog sets a function called for every rejected delivery.
+func WithErrorLog(fn func(*http.Request, error)) Option {
+	return func(h *Handler) {
+		h.errorLog = fn
+	}
+}
+
+// WithEventStore persists every accepted delivery to store before dispatch.
+// The store then deduplicates by event ID across restarts. Once an event is
+// stored the delivery is acknowledged even if a callback fails; the failure is
+// reported through WithErrorLog and the event can be re-processed with Replay.
+func WithEventStore(store EventStore) Option {
+	return func(h *Handler) {
+		h.store = store
+	}
+}
+
+// NewHandler creates a webhook receiver for deliveries signed with secret.
+// A handler with an empty secret rejects every delivery with ErrNoSecret
+// unless WithoutVerification is set.
+func NewHandler(secret string, opts ...Option) *Handler {
+	h := &Handler{
+		secret:       secret,
+		scheme:       DefaultScheme,
+		tolerance:    DefaultTolerance,
+		maxBodyBytes: defaultMaxBodyBytes,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.scheme = h.scheme.withDefaults()
+	window := h.tolerance
+	if window <= 0 {
+		window = DefaultTolerance
+	}
+	h.replay = newReplayCache(2 * window)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.reject(w, r, http.StatusMethodNotAllowed, errors.New("sandbox0webhook: method not allowed"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes+1))
+	if err != nil {
+		h.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(body)) > h.maxBodyBytes {
+		h.reject(w, r, http.StatusRequestEntityTooLarge, errors.New("sandbox0webhook: payload too large"))
+		return
+	}
+
+	now := h.now()
+	if !h.unverified {
+		if h.secret == "" {
+			h.reject(w, r, http.StatusInternalServerError, ErrNoSecret)
+			return
+		}
+		if err := h.scheme.Verify(h.secret, r.Header, body, h.tolerance, now); err != nil {
+			h.reject(w, r, http.StatusUnauthorized, err)
+			return
+		}
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		h.reject(w, r, http.StatusBadRequest, fmt.Errorf("sandbox0webhook: decode payload: %w", err))
+		return
+	}
+	if payload.EventID == "" {
+		payload.EventID = strings.TrimSpace(r.Header.Get(EventIDHeader))
+	}
+	event, err := payload.Event()
+	if err != nil {
+		h.reject(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if h.store != nil {
+		_, added, err := h.store.Append(r.Context(), payload)
+		if err != nil {
+			h.reject(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if !added {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if err := h.deliver(r, event); err != nil && h.errorLog != nil {
+			h.errorLog(r, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	key := h.deliveryKey(r.Header, payload)
+	if !h.replay.reserve(key, now) {
+		// Already processed (or being processed): acknowledge so it is not retried.
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := h.deliver(r, event); err != nil {
+		h.replay.release(key)
+		status := http.StatusInternalServerError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusServiceUnavailable
+		}
+		h.reject(w, r, status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deliver(r *http.Request, event Event) error {
+	ctx := r.Context()
+	if err := h.handlers.Dispatch(ctx, event); err != nil {
+		return err
+	}
+	if h.broker != nil {
+		if err := h.broker.Publish(ctx, event); err != nil {
+			if !errors.Is(err, ErrSubscriberFull) {
+				return err
+			}
+			if h.errorLog != nil {
+				h.errorLog(r, err)
+			}
+		}
+	}
+	if h.events == nil {
+		return nil
+	}
+	select {
+	case h.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if h.errorLog != nil {
+		h.errorLog(r, err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (h *Handler) deliveryKey(header http.Header, payload Payload) string {
+	if payload.EventID != "" {
+		return payload.EventID
+	}
+	return header.Get(h.scheme.TimestampHeader) + "/" + header.Get(h.scheme.SignatureHeader)
+}
+
+// replayCache remembers delivery keys for a window to drop replays and retries
+// of already processed events.
+type replayCache struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   map[string]time.Time
+}
+
+func newReplayCache(window time.Duration) *replayCache {
+	return &replayCache{window: window, seen: map[string]time.Time{}}
+}
+
+func (c *replayCache) reserve(key string, now time.Time) bool {
+	if key == "/" {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, at := range c.seen {
+		if now.Sub(at) > c.window {
+			delete(c.seen, k)
+		}
+	}
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = now
+	return true
+}
+
+func (c *replayCache) release(key string) {
+	c.mu.Lock()
+	delete(c.seen, key)
+	c.mu.Unlock()
+}
@@ -0,0 +1,129 @@
+package sandbox0webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default header names of a webhook delivery. They are assumed, not taken
+// from the Sandbox0 API spec; see DefaultScheme.
+const (
+	SignatureHeader = "X-Sandbox0-Signature"
+	TimestampHeader = "X-Sandbox0-Timestamp"
+	EventIDHeader   = "X-Sandbox0-Event-Id"
+	EventTypeHeader = "X-Sandbox0-Event-Type"
+)
+
+const signaturePrefix = "sha256="
+
+// DefaultTolerance is the maximum accepted clock skew between the signed timestamp and now.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingSignature is returned when a signed delivery lacks signature headers.
+	ErrMissingSignature = errors.New("sandbox0webhook: missing signature")
+	// ErrInvalidSignature is returned when the signature does not match the payload.
+	ErrInvalidSignature = errors.New("sandbox0webhook: invalid signature")
+	// ErrTimestampOutOfRange is returned when the signed timestamp is outside the tolerance.
+	ErrTimestampOutOfRange = errors.New("sandbox0webhook: timestamp outside tolerance")
+	// ErrNoSecret is returned for every delivery to a Handler created without a
+	// secret unless WithoutVerification is set.
+	ErrNoSecret = errors.New("sandbox0webhook: no secret configured")
+)
+
+// Scheme describes how a delivery is signed. The signed time is carried in
+// TimestampHeader as Unix seconds and the result of Sign in SignatureHeader.
+// Empty fields take their value from DefaultScheme.
+type Scheme struct {
+	SignatureHeader string
+	TimestampHeader string
+	// Sign returns the SignatureHeader value for body signed at timestamp.
+	Sign func(secret string, timestamp time.Time, body []byte) string
+}
+
+// DefaultScheme is the signing scheme assumed for Sandbox0 deliveries: the
+// API spec only says that the webhook secret signs payloads. Use WithScheme
+// and WithSimulatorScheme when the server signs differently.
+var DefaultScheme = Scheme{
+	SignatureHeader: SignatureHeader,
+	TimestampHeader: TimestampHeader,
+	Sign:            Sign,
+}
+
+func (sc Scheme) withDefaults() Scheme {
+	if sc.SignatureHeader == "" {
+		sc.SignatureHeader = DefaultScheme.SignatureHeader
+	}
+	if sc.TimestampHeader == "" {
+		sc.TimestampHeader = DefaultScheme.TimestampHeader
+	}
+	if sc.Sign == nil {
+		sc.Sign = DefaultScheme.Sign
+	}
+	return sc
+}
+
+// SetHeaders sets the timestamp and signature headers for body on header.
+func (sc Scheme) SetHeaders(header http.Header, secret string, timestamp time.Time, body []byte) {
+	sc = sc.withDefaults()
+	header.Set(sc.TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(sc.SignatureHeader, sc.Sign(secret, timestamp, body))
+}
+
+// Verify checks the signature headers of a delivery against body.
+// A tolerance of zero or less disables the timestamp window check.
+func (sc Scheme) Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	sc = sc.withDefaults()
+	signature := strings.TrimSpace(header.Get(sc.SignatureHeader))
+	rawTimestamp := strings.TrimSpace(header.Get(sc.TimestampHeader))
+	if signature == "" || rawTimestamp == "" {
+		return ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	timestamp := time.Unix(unix, 0)
+	if tolerance > 0 {
+		skew := now.Sub(timestamp)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > tolerance {
+			return ErrTimestampOutOfRange
+		}
+	}
+	expected := sc.Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Sign computes the signature header value of DefaultScheme for body sent at
+// timestamp: "sha256=" and the hex HMAC-SHA256 of "<unix seconds>.<body>".
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetSignatureHeaders sets the DefaultScheme timestamp and signature headers
+// for body on header.
+func SetSignatureHeaders(header http.Header, secret string, timestamp time.Time, body []byte) {
+	DefaultScheme.SetHeaders(header, secret, timestamp, body)
+}
+
+// Verify checks the DefaultScheme signature headers of a delivery against body.
+// A tolerance of zero or less disables the timestamp window check.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	return DefaultScheme.Verify(secret, header, body, tolerance, now)
+}
@@ -0,0 +1,280 @@
+package sandbox0webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault selects a delivery fault injected by the Simulator.
+type Fault uint8
+
+// Faults that can be combined on a Step.
+const (
+	// FaultDuplicate delivers the event a second time with the same event ID.
+	FaultDuplicate Fault = 1 << iota
+	// FaultBadSignature signs the payload with a wrong secret.
+	FaultBadSignature
+	// FaultMissingSignature omits the signature headers.
+	FaultMissingSignature
+	// FaultStaleTimestamp signs the payload with a timestamp outside DefaultTolerance.
+	FaultStaleTimestamp
+)
+
+// Step is one event in a simulated delivery sequence.
+type Step struct {
+	Type EventType
+	// Data is marshaled into the payload "data" field.
+	Data any
+	// Delay is waited before the step is sent.
+	Delay time.Duration
+	// Faults are injected when the step is sent.
+	Faults Fault
+}
+
+// WithFaults returns a copy of the step with faults added.
+func (s Step) WithFaults(faults Fault) Step {
+	s.Faults |= faults
+	return s
+}
+
+// SandboxClaimedStep returns a sandbox.claimed step.
+func SandboxClaimedStep(template string) Step {
+	return Step{Type: EventSandboxClaimed, Data: SandboxData{Template: template}}
+}
+
+// ProcessStartedStep returns a process.started step.
+func ProcessStartedStep(contextID string) Step {
+	return Step{Type: EventProcessStarted, Data: ProcessData{ContextID: contextID}}
+}
+
+// ProcessExitedStep returns a process.exited step with exit code zero.
+func ProcessExitedStep(contextID string) Step {
+	code := 0
+	return Step{Type: EventProcessExited, Data: ProcessData{ContextID: contextID, ExitCode: &code}}
+}
+
+// ProcessCrashedStep returns a process.crashed step with the given exit code.
+func ProcessCrashedStep(contextID string, exitCode int) Step {
+	return Step{Type: EventProcessCrashed, Data: ProcessData{ContextID: contextID, ExitCode: &exitCode}}
+}
+
+// SandboxPausedStep returns a sandbox.paused step.
+func SandboxPausedStep() Step {
+	return Step{Type: EventSandboxPaused}
+}
+
+// SandboxResumedStep returns a sandbox.resumed step.
+func SandboxResumedStep() Step {
+	return Step{Type: EventSandboxResumed}
+}
+
+// FileModifiedStep returns a file.modified step. event is a file watch event
+// name such as "create", "write", "rename", "chmod" or "remove".
+func FileModifiedStep(path, event string) Step {
+	return Step{Type: EventFileModified, Data: FileData{Path: path, Event: event}}
+}
+
+// AllEventSteps returns one step for every event type, in lifecycle order.
+func AllEventSteps() []Step {
+	return []Step{
+		SandboxClaimedStep("default"),
+		ProcessStartedStep("ctx-sim"),
+		FileModifiedStep("/workspace/sim.txt", "write"),
+		ProcessExitedStep("ctx-sim"),
+		ProcessCrashedStep("ctx-sim", 2),
+		SandboxPausedStep(),
+		SandboxResumedStep(),
+	}
+}
+
+// Reorder returns steps rearranged by index, e.g. Reorder(steps, 0, 2, 1)
+// swaps the second and third steps. Indexes not listed are dropped.
+func Reorder(steps []Step, order ...int) []Step {
+	out := make([]Step, 0, len(order))
+	for _, i := range order {
+		if i >= 0 && i < len(steps) {
+			out = append(out, steps[i])
+		}
+	}
+	return out
+}
+
+// Delivery records one HTTP attempt made by the Simulator.
+type Delivery struct {
+	Payload    Payload
+	Fault      Fault
+	StatusCode int
+	Err        error
+}
+
+// Simulator signs and POSTs webhook events the way Sandbox0 does, for testing receivers.
+type Simulator struct {
+	URL       string
+	Secret    string
+	SandboxID string
+
+	httpClient *http.Client
+	now        func() time.Time
+	scheme     Scheme
+}
+
+// SimulatorOption configures a Simulator.
+type SimulatorOption func(*Simulator)
+
+// WithSimulatorHTTPClient sets the HTTP client used for deliveries.
+func WithSimulatorHTTPClient(client *http.Client) SimulatorOption {
+	return func(s *Simulator) {
+		s.httpClient = client
+	}
+}
+
+// WithSimulatorSandboxID sets the sandbox ID reported in payloads.
+func WithSimulatorSandboxID(sandboxID string) SimulatorOption {
+	return func(s *Simulator) {
+		s.SandboxID = sandboxID
+	}
+}
+
+// WithSimulatorScheme signs deliveries with scheme instead of DefaultScheme.
+func WithSimulatorScheme(scheme Scheme) SimulatorOption {
+	return func(s *Simulator) {
+		s.scheme = scheme
+	}
+}
+
+// WithSimulatorClock overrides the time source used for timestamps.
+func WithSimulatorClock(now func() time.Time) SimulatorOption {
+	return func(s *Simulator) {
+		s.now = now
+	}
+}
+
+// NewSimulator creates a simulator delivering to url, signing with secret.
+func NewSimulator(url, secret string, opts ...SimulatorOption) *Simulator {
+	s := &Simulator{
+		URL:        url,
+		Secret:     secret,
+		SandboxID:  "sb-simulated",
+		httpClient: http.DefaultClient,
+		now:        time.Now,
+		scheme:     DefaultScheme,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Payload builds a payload for step with a fresh event ID.
+func (s *Simulator) Payload(step Step) (Payload, error) {
+	payload := Payload{
+		EventID:   newEventID(),
+		EventType: step.Type,
+		SandboxID: s.SandboxID,
+		Timestamp: s.now().UTC(),
+	}
+	if step.Data != nil {
+		data, err := json.Marshal(step.Data)
+		if err != nil {
+			return Payload{}, fmt.Errorf("sandbox0webhook: encode %s data: %w", step.Type, err)
+		}
+		payload.Data = data
+	}
+	return payload, nil
+}
+
+// Send delivers a single step, applying its faults.
+// It returns one Delivery per HTTP attempt.
+func (s *Simulator) Send(ctx context.Context, step Step) ([]Delivery, error) {
+	payload, err := s.Payload(step)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []Delivery{s.Deliver(ctx, payload, step.Faults&^FaultDuplicate)}
+	if step.Faults&FaultDuplicate != 0 {
+		deliveries = append(deliveries, s.Deliver(ctx, payload, FaultDuplicate))
+	}
+	return deliveries, nil
+}
+
+// Run sends steps in order, honoring delays, and returns every attempt.
+// Delivery failures are recorded in Delivery.Err and do not stop the run.
+func (s *Simulator) Run(ctx context.Context, steps []Step) ([]Delivery, error) {
+	var deliveries []Delivery
+	for _, step := range steps {
+		if step.Delay > 0 {
+			timer := time.NewTimer(step.Delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return deliveries, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		sent, err := s.Send(ctx, step)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, sent...)
+	}
+	return deliveries, nil
+}
+
+// Deliver POSTs payload with the given faults applied.
+func (s *Simulator) Deliver(ctx context.Context, payload Payload, fault Fault) Delivery {
+	delivery := Delivery{Payload: payload, Fault: fault}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Err = err
+		return delivery
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Err = err
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, payload.EventID)
+	req.Header.Set(EventTypeHeader, string(payload.EventType))
+
+	signedAt := s.now()
+	if fault&FaultStaleTimestamp != 0 {
+		signedAt = signedAt.Add(-2 * DefaultTolerance)
+	}
+	secret := s.Secret
+	if fault&FaultBadSignature != 0 {
+		secret = "invalid-" + secret
+	}
+	if fault&FaultMissingSignature == 0 && (s.Secret != "" || fault&FaultBadSignature != 0) {
+		s.scheme.SetHeaders(req.Header, secret, signedAt, body)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		delivery.Err = err
+		return delivery
+	}
+	defer resp.Body.Close()
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		delivery.Err = fmt.Errorf("sandbox0webhook: receiver returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return delivery
+}
+
+func newEventID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("evt_%d", time.Now().UnixNano())
+	}
+	return "evt_" + hex.EncodeToString(b[:])
+}
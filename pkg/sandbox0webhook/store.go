@@ -0,0 +1,276 @@
+package sandbox0webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is a stored webhook delivery.
+type Record struct {
+	// Seq is the store-wide position of the record, starting at 1.
+	// It is the cursor used by Since and Replay.
+	Seq        uint64    `json:"seq"`
+	ReceivedAt time.Time `json:"received_at"`
+	Payload    Payload   `json:"payload"`
+}
+
+// Event decodes the stored payload into a typed Event.
+func (r Record) Event() (Event, error) {
+	return r.Payload.Event()
+}
+
+// EventStore persists webhook deliveries for deduplication and replay.
+// Implementations must be safe for concurrent use.
+type EventStore interface {
+	// Append stores payload unless a payload with the same event ID is already
+	// stored. It returns the stored record and whether it was newly added.
+	Append(ctx context.Context, payload Payload) (Record, bool, error)
+	// Since returns up to limit records with Seq greater than cursor, in arrival order.
+	// A limit of zero or less returns all remaining records.
+	Since(ctx context.Context, cursor uint64, limit int) ([]Record, error)
+	// SandboxEvents returns the records of one sandbox with Seq greater than
+	// cursor, ordered by event timestamp (arrival order breaks ties).
+	SandboxEvents(ctx context.Context, sandboxID string, cursor uint64) ([]Record, error)
+}
+
+// MemoryStore is an in-memory EventStore.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records []Record
+	byID    map[string]uint64
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byID: map[string]uint64{}, now: time.Now}
+}
+
+// Append implements EventStore.
+func (s *MemoryStore) Append(_ context.Context, payload Payload) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, added := s.appendLocked(payload)
+	return record, added, nil
+}
+
+func (s *MemoryStore) appendLocked(payload Payload) (Record, bool) {
+	if payload.EventID != "" {
+		if seq, ok := s.byID[payload.EventID]; ok {
+			return s.records[seq-1], false
+		}
+	}
+	record := Record{
+		Seq:        uint64(len(s.records)) + 1,
+		ReceivedAt: s.now().UTC(),
+		Payload:    payload,
+	}
+	s.insertLocked(record)
+	return record, true
+}
+
+func (s *MemoryStore) insertLocked(record Record) {
+	s.records = append(s.records, record)
+	if record.Payload.EventID != "" {
+		s.byID[record.Payload.EventID] = record.Seq
+	}
+}
+
+// Since implements EventStore.
+func (s *MemoryStore) Since(_ context.Context, cursor uint64, limit int) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if cursor >= uint64(len(s.records)) {
+		return nil, nil
+	}
+	remaining := s.records[cursor:]
+	if limit > 0 && len(remaining) > limit {
+		remaining = remaining[:limit]
+	}
+	return append([]Record(nil), remaining...), nil
+}
+
+// SandboxEvents implements EventStore.
+func (s *MemoryStore) SandboxEvents(_ context.Context, sandboxID string, cursor uint64) ([]Record, error) {
+	s.mu.RLock()
+	var out []Record
+	for _, record := range s.records {
+		if record.Seq > cursor && record.Payload.SandboxID == sandboxID {
+			out = append(out, record)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Payload.Timestamp.Before(out[j].Payload.Timestamp)
+	})
+	return out, nil
+}
+
+// FileStore is an EventStore backed by an append-only JSON Lines file.
+// The file is read on open to rebuild the index; records are also kept in memory.
+type FileStore struct {
+	mem  *MemoryStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileStore opens or creates a JSON Lines store at path. Records of any
+// size are read. A last record cut short, such as by a crash during Append,
+// is removed from the file.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := loadRecords(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("sandbox0webhook: %s: %w", path, err)
+	}
+	return &FileStore{mem: mem, file: file}, nil
+}
+
+// loadRecords reads the records of a store file. Every record written by
+// Append ends with a newline, so a last line without one was cut short; it is
+// truncated away unless it still holds a whole record, which gets its newline.
+func loadRecords(file *os.File) (*MemoryStore, error) {
+	mem := NewMemoryStore()
+	reader := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return mem, nil
+		}
+		complete := data[len(data)-1] == '\n'
+		if text := bytes.TrimSpace(data); len(text) > 0 {
+			var record Record
+			err := json.Unmarshal(text, &record)
+			if err == nil && record.Seq != uint64(len(mem.records))+1 {
+				err = fmt.Errorf("unexpected seq %d", record.Seq)
+			}
+			switch {
+			case err != nil && complete:
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			case err != nil:
+				if err := file.Truncate(offset); err != nil {
+					return nil, err
+				}
+				return mem, nil
+			case !complete:
+				if _, err := file.Write([]byte{'\n'}); err != nil {
+					return nil, err
+				}
+			}
+			mem.insertLocked(record)
+		}
+		if !complete {
+			return mem, nil
+		}
+		offset += int64(len(data))
+	}
+}
+
+// Append implements EventStore. New records are synced to disk before returning.
+func (s *FileStore) Append(_ context.Context, payload Payload) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return Record{}, false, errors.New("sandbox0webhook: file store is closed")
+	}
+
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+	if payload.EventID != "" {
+		if seq, ok := s.mem.byID[payload.EventID]; ok {
+			return s.mem.records[seq-1], false, nil
+		}
+	}
+	record := Record{
+		Seq:        uint64(len(s.mem.records)) + 1,
+		ReceivedAt: s.mem.now().UTC(),
+		Payload:    payload,
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, false, err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return Record{}, false, err
+	}
+	if err := s.file.Sync(); err != nil {
+		return Record{}, false, err
+	}
+	s.mem.insertLocked(record)
+	return record, true, nil
+}
+
+// Since implements EventStore.
+func (s *FileStore) Since(ctx context.Context, cursor uint64, limit int) ([]Record, error) {
+	return s.mem.Since(ctx, cursor, limit)
+}
+
+// SandboxEvents implements EventStore.
+func (s *FileStore) SandboxEvents(ctx context.Context, sandboxID string, cursor uint64) ([]Record, error) {
+	return s.mem.SandboxEvents(ctx, sandboxID, cursor)
+}
+
+// Close closes the underlying file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Replay calls fn for every record after cursor, in arrival order, and returns
+// the cursor of the last record handled. Replay stops at the first error from fn,
+// returning the cursor of the last successfully handled record.
+func Replay(ctx context.Context, store EventStore, cursor uint64, fn func(context.Context, Record) error) (uint64, error) {
+	const pageSize = 256
+	for {
+		records, err := store.Since(ctx, cursor, pageSize)
+		if err != nil {
+			return cursor, err
+		}
+		for _, record := range records {
+			if err := ctx.Err(); err != nil {
+				return cursor, err
+			}
+			if err := fn(ctx, record); err != nil {
+				return cursor, err
+			}
+			cursor = record.Seq
+		}
+		if len(records) < pageSize {
+			return cursor, nil
+		}
+	}
+}
+
+// ReplayHandlers replays stored events after cursor through handlers.
+func ReplayHandlers(ctx context.Context, store EventStore, cursor uint64, handlers Handlers) (uint64, error) {
+	return Replay(ctx, store, cursor, func(ctx context.Context, record Record) error {
+		event, err := record.Event()
+		if err != nil {
+			return err
+		}
+		return handlers.Dispatch(ctx, event)
+	})
+}
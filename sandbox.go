@@ -3,11 +3,14 @@ package sandbox0
 import (
 	"context"
 	"errors"
+	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/shlex"
 	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+	"github.com/sandbox0-ai/sdk-go/pkg/output"
 )
 
 // Sandbox is a convenience wrapper for sandbox-scoped operations.
@@ -18,9 +21,14 @@ type Sandbox struct {
 	PodName   string
 	Status    string
 
-	client            *Client
-	replContextByLang map[string]string
-	mu                sync.Mutex
+	client *Client
+	// replContexts maps a REPL cache key (language plus context options) to a context ID.
+	replContexts map[string]string
+	// replStreams holds the streams RunStream reads REPL contexts through, by context ID.
+	replStreams map[string]*ContextStream
+	// jobs holds the jobs started or discovered through this Sandbox, by context ID.
+	jobs map[string]*Job
+	mu   sync.Mutex
 }
 
 // RunResult represents REPL execution output.
@@ -28,13 +36,56 @@ type RunResult struct {
 	SandboxID string
 	ContextID string
 	OutputRaw string
+	// Stdout holds the execution output. REPL output is not split by stream,
+	// so Stderr is empty unless the server reports it separately.
+	Stdout    string
+	Stderr    string
+	StartedAt time.Time
+	Duration  time.Duration
+	// Output is the rendered output with echoed input and prompts removed.
+	Output string
+	// Error is the exception raised by the code, for languages whose
+	// tracebacks are recognized (Python and Node). It is nil otherwise.
+	Error *REPLError
+	// Truncated reports whether output was dropped from OutputRaw, Stdout
+	// and Stderr to stay within WithMaxOutputBytes.
+	Truncated bool
+}
+
+// Text returns OutputRaw rendered through a terminal screen model, with
+// escape sequences applied and removed. See output.Render.
+func (r RunResult) Text() string {
+	return output.Render(r.OutputRaw)
 }
 
 // CmdResult represents CMD execution output.
 type CmdResult struct {
 	SandboxID string
 	ContextID string
+	// OutputRaw holds stdout and stderr interleaved in arrival order.
 	OutputRaw string
+	// Stdout and Stderr are split by the WebSocket output source when the
+	// output is streamed; the server reports all output of a command on a
+	// PTY as stdout. Output collected by the server without streaming is not
+	// separated: Stdout then holds the same interleaved output as OutputRaw,
+	// stderr included, and Stderr is empty.
+	Stdout string
+	Stderr string
+	// ExitCode is the command exit status, or -1 when it is unknown. The
+	// context API does not report it for commands that are waited for without
+	// streaming; see WithCmdExitStatus.
+	ExitCode  int
+	StartedAt time.Time
+	Duration  time.Duration
+	// Truncated reports whether output was dropped from OutputRaw, Stdout
+	// and Stderr to stay within WithCmdMaxOutputBytes.
+	Truncated bool
+}
+
+// Text returns OutputRaw rendered through a terminal screen model, with
+// escape sequences applied and removed. See output.Render.
+func (r CmdResult) Text() string {
+	return output.Render(r.OutputRaw)
 }
 
 type runOptions struct {
@@ -44,6 +95,11 @@ type runOptions struct {
 	cwd            *string
 	envVars        *map[string]string
 	ptySize        *apispec.PTYSize
+	prompt         string
+	failOnError    bool
+	replConfig     *apispec.REPLConfig
+	sink           io.Writer
+	maxOutputBytes int
 }
 
 // RunOption configures sandbox Run behavior.
@@ -96,32 +152,169 @@ func WithPTYSize(rows, cols uint16) RunOption {
 	}
 }
 
+// WithREPLConfig starts the REPL context with config instead of the
+// configuration registered for the language or its built-in preset.
+func WithREPLConfig(config apispec.REPLConfig) RunOption {
+	return func(opts *runOptions) {
+		config := cloneREPLConfig(config)
+		opts.replConfig = &config
+	}
+}
+
+// WithREPLPrompt sets the prompt token stripped from Run output, matching the
+// custom_prompt of the context REPLPromptConfig. Default prompts of Python,
+// IPython and Node are always recognized.
+func WithREPLPrompt(prompt string) RunOption {
+	return func(opts *runOptions) {
+		opts.prompt = prompt
+	}
+}
+
+// WithRunFailOnError makes Run return a *RunError when the executed code
+// raised an exception, in addition to the result.
+func WithRunFailOnError() RunOption {
+	return func(opts *runOptions) {
+		opts.failOnError = true
+	}
+}
+
+// WithOutputSink makes Run and RunStream write all output to w, such as an
+// *os.File or a rotating log writer, including output dropped from the result
+// by WithMaxOutputBytes. If a write fails, later output is not written to w
+// and the error is returned once the execution completes.
+func WithOutputSink(w io.Writer) RunOption {
+	return func(opts *runOptions) {
+		opts.sink = w
+	}
+}
+
+// WithMaxOutputBytes limits the output kept in RunResult.OutputRaw, Stdout and
+// Stderr to about n bytes each: the first and the last n/2 bytes, with a
+// marker telling how many bytes were dropped between them. RunResult.Truncated
+// reports whether output was dropped. Combine it with WithOutputSink to keep
+// the full output. Run receives the output in one response, so it bounds the
+// result only; RunStream also bounds the memory used while the code runs.
+func WithMaxOutputBytes(n int) RunOption {
+	return func(opts *runOptions) {
+		opts.maxOutputBytes = n
+	}
+}
+
 // Run executes input in a REPL context.
+// The context is started with the REPL configuration from WithREPLConfig or
+// Client.RegisterLanguage, in that order. Otherwise the server's REPL is used
+// for python, node and bash, and a built-in preset for the other languages
+// that have one (see REPLPreset).
+// Python and Node exceptions are parsed into RunResult.Error; err stays nil
+// for them unless WithRunFailOnError is set.
+//
+// A cached context that no longer exists is recreated and input runs in the
+// new one. If the exec fails otherwise and the context stopped running, it
+// is restarted for the next Run and the error is returned: the input may have
+// stopped the REPL, so it is not run twice.
+//
+// If ctx is done before the code finishes, the REPL is interrupted as
+// described for ContextExec.
 func (s *Sandbox) Run(ctx context.Context, language, input string, opts ...RunOption) (RunResult, error) {
 	if strings.TrimSpace(input) == "" {
 		return RunResult{}, errors.New("input cannot be empty")
 	}
 
-	options := runOptions{}
-	for _, opt := range opts {
-		opt(&options)
-	}
-
-	contextID, err := s.ensureReplContext(ctx, language, options)
+	language, options := s.runOptions(language, opts)
+	contextID, cached, err := s.ensureReplContext(ctx, language, options)
 	if err != nil {
 		return RunResult{}, err
 	}
 
+	startedAt := time.Now()
 	execResp, err := s.ContextExec(ctx, contextID, input)
+	if err != nil && cached {
+		// The cached context may have expired, crashed or been deleted.
+		healedID, healErr := s.healReplContext(ctx, contextID, language, options, err)
+		if healErr != nil {
+			return RunResult{}, healErr
+		}
+		if !isNotFound(err) {
+			// The input may have reached the REPL and stopped it, so it is
+			// not run again.
+			return RunResult{}, err
+		}
+		contextID = healedID
+		startedAt = time.Now()
+		execResp, err = s.ContextExec(ctx, contextID, input)
+	}
 	if err != nil {
 		return RunResult{}, err
 	}
 
-	return RunResult{
+	result := RunResult{
 		SandboxID: s.ID,
 		ContextID: contextID,
 		OutputRaw: execResp.OutputRaw,
-	}, nil
+		Stdout:    execResp.OutputRaw,
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+	}
+	if options.maxOutputBytes > 0 {
+		raw := newOutputBuffer(options.maxOutputBytes)
+		_, _ = raw.WriteString(execResp.OutputRaw)
+		result.OutputRaw = raw.String()
+		result.Stdout = result.OutputRaw
+		result.Truncated = raw.Truncated()
+	}
+	err = s.parseRunOutput(&result, language, input, options)
+	if options.sink != nil {
+		sink := &outputSink{w: options.sink}
+		_, _ = sink.WriteString(execResp.OutputRaw)
+		if sinkErr := sink.Err(); sinkErr != nil {
+			err = errors.Join(err, sinkErr)
+		}
+	}
+	return result, err
+}
+
+// runOptions applies opts and resolves the REPL configuration for language.
+func (s *Sandbox) runOptions(language string, opts []RunOption) (string, runOptions) {
+	options := runOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	language = normalizeLanguage(language)
+	if options.replConfig == nil {
+		if config, ok := s.client.languageConfig(language); ok {
+			options.replConfig = &config
+		}
+	}
+	return language, options
+}
+
+// parseRunOutput fills the Output and Error fields of result from its raw
+// output. It returns a *RunError when WithRunFailOnError is set and the code
+// raised an exception.
+func (s *Sandbox) parseRunOutput(result *RunResult, language, input string, options runOptions) error {
+	var prompts []string
+	if options.prompt != "" {
+		prompts = append(prompts, options.prompt)
+	}
+	if options.replConfig != nil {
+		prompts = append(prompts, replPrompts(*options.replConfig)...)
+	}
+	lines := cleanREPLOutput(result.OutputRaw, language, input, prompts...)
+	if len(lines) > 0 {
+		result.Output = strings.Join(lines, "\n") + "\n"
+	}
+	if parse := replErrorParsers[language]; parse != nil {
+		result.Error = parse(lines)
+	}
+	if options.failOnError && result.Error != nil {
+		return &RunError{
+			SandboxID: s.ID,
+			ContextID: result.ContextID,
+			Language:  language,
+			Err:       result.Error,
+		}
+	}
+	return nil
 }
 
 type cmdOptions struct {
@@ -132,6 +325,12 @@ type cmdOptions struct {
 	cwd            *string
 	envVars        *map[string]string
 	ptySize        *apispec.PTYSize
+	exitStatus     *bool
+	// stdout and stderr, when set, also receive output as it arrives.
+	stdout         io.Writer
+	stderr         io.Writer
+	sink           io.Writer
+	maxOutputBytes int
 }
 
 // CmdOption configures sandbox Cmd behavior.
@@ -152,6 +351,17 @@ func WithCmdWait(wait bool) CmdOption {
 	}
 }
 
+// WithCmdExitStatus sets whether Cmd reports the command exit status.
+// Default is false. When enabled, the command runs under /bin/sh, which
+// writes its status to standard error where it is removed from the output,
+// and Cmd streams output over the context WebSocket. The image must provide
+// a POSIX shell.
+func WithCmdExitStatus(enabled bool) CmdOption {
+	return func(opts *cmdOptions) {
+		opts.exitStatus = &enabled
+	}
+}
+
 // WithCmdTTL sets TTL in seconds for created CMD contexts.
 func WithCmdTTL(ttlSec int32) CmdOption {
 	return func(opts *cmdOptions) {
@@ -192,13 +402,122 @@ func WithCmdPTYSize(rows, cols uint16) CmdOption {
 	}
 }
 
+// WithCmdOutputSink makes Cmd, Exec and CmdStream write all output to w, such
+// as an *os.File or a rotating log writer, with stdout and stderr interleaved
+// in arrival order. It receives output dropped from the result by
+// WithCmdMaxOutputBytes too. If a write fails, later output is not written to
+// w and the error is returned once the command exits.
+func WithCmdOutputSink(w io.Writer) CmdOption {
+	return func(opts *cmdOptions) {
+		opts.sink = w
+	}
+}
+
+// WithCmdMaxOutputBytes limits the output kept in CmdResult.OutputRaw, Stdout
+// and Stderr to about n bytes each: the first and the last n/2 bytes, with a
+// marker telling how many bytes were dropped between them. CmdResult.Truncated
+// reports whether output was dropped. Combine it with WithCmdOutputSink to
+// keep the full output.
+func WithCmdMaxOutputBytes(n int) CmdOption {
+	return func(opts *cmdOptions) {
+		opts.maxOutputBytes = n
+	}
+}
+
 // Cmd executes a command in a CMD context.
-// By default, it waits for command completion. Use WithCmdWait(false) for async execution.
+// cmd is split into arguments with shell-like quoting rules, but no shell runs
+// it: pipes, redirections, command lists, variables and globs are passed as
+// literal text. Cmd returns an error wrapping ErrShellSyntax when cmd has an
+// operator such as |, &&, ; or > as a separate word outside quotes. Use Exec
+// with Shell to run a shell command line, or Exec with an argv to pass
+// arguments without any quoting.
+// By default, it waits for command completion and returns the output
+// collected by the server, with stdout and stderr interleaved. A ctx deadline
+// also limits the TTL of the context to the time left plus three cancel grace
+// periods, so the server stops the command once it passes.
+// With WithCmdExitStatus(true) or WithCmdOutputSink, output is streamed over
+// the context WebSocket instead, split into stdout and stderr, and a ctx that
+// is done first stops the command as described for CommandContext. If the
+// command is known to exit with a non-zero status, the result is returned
+// along with an *ExitError.
+// Use WithCmdWait(false) for async execution.
 // The context is not automatically deleted; use DeleteContext to clean up when done.
 func (s *Sandbox) Cmd(ctx context.Context, cmd string, opts ...CmdOption) (CmdResult, error) {
-	if strings.TrimSpace(cmd) == "" {
+	options, err := parseCmdOptions(cmd, opts)
+	if err != nil {
+		return CmdResult{}, err
+	}
+	return s.execCmd(ctx, options)
+}
+
+// Exec executes the program argv[0] with the arguments argv[1:] in a CMD
+// context. The arguments reach the program exactly as given, so values from
+// untrusted input need no quoting. It accepts the options of Cmd and behaves
+// like it otherwise; WithCommand is ignored.
+func (s *Sandbox) Exec(ctx context.Context, argv []string, opts ...CmdOption) (CmdResult, error) {
+	if len(argv) == 0 || strings.TrimSpace(argv[0]) == "" {
 		return CmdResult{}, errors.New("command cannot be empty")
 	}
+	options := cmdOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.command = argv
+	return s.execCmd(ctx, options)
+}
+
+// execCmd runs the command in options, waiting for it unless WithCmdWait(false)
+// was given.
+func (s *Sandbox) execCmd(ctx context.Context, options cmdOptions) (CmdResult, error) {
+	wait := options.wait == nil || *options.wait
+	if wait && options.streamed() {
+		return s.runCmd(ctx, options)
+	}
+
+	req := cmdContextRequest(options.command, options)
+	if wait {
+		req.WaitUntilDone = apispec.NewOptBool(true)
+		if ttlSec := deadlineTTL(ctx, s.client.cancelGrace(), options.ttlSec); ttlSec != nil {
+			req.TTLSec = apispec.NewOptInt32(*ttlSec)
+		}
+	}
+	startedAt := time.Now()
+	contextResp, err := s.CreateContext(ctx, req)
+	if err != nil {
+		return CmdResult{}, err
+	}
+	if contextResp == nil {
+		return CmdResult{}, errors.New("create context returned nil response")
+	}
+
+	raw := newOutputBuffer(options.maxOutputBytes)
+	if value, ok := contextResp.OutputRaw.Get(); ok {
+		_, _ = raw.WriteString(value)
+	}
+
+	return CmdResult{
+		SandboxID: s.ID,
+		ContextID: contextResp.ID,
+		OutputRaw: raw.String(),
+		Stdout:    raw.String(),
+		ExitCode:  -1,
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+		Truncated: raw.Truncated(),
+	}, nil
+}
+
+// streamed reports whether options need the command output streamed over the
+// context WebSocket rather than collected by the server.
+func (o cmdOptions) streamed() bool {
+	return (o.exitStatus != nil && *o.exitStatus) || o.sink != nil || o.stdout != nil || o.stderr != nil
+}
+
+// parseCmdOptions applies opts and splits cmd into argv unless WithCommand is set.
+func parseCmdOptions(cmd string, opts []CmdOption) (cmdOptions, error) {
+	if strings.TrimSpace(cmd) == "" {
+		return cmdOptions{}, errors.New("command cannot be empty")
+	}
 
 	options := cmdOptions{}
 	for _, opt := range opts {
@@ -206,24 +525,28 @@ func (s *Sandbox) Cmd(ctx context.Context, cmd string, opts ...CmdOption) (CmdRe
 	}
 
 	if options.command == nil {
+		if err := checkShellSyntax(cmd); err != nil {
+			return cmdOptions{}, err
+		}
 		parsed, err := parseCommand(cmd)
 		if err != nil {
-			return CmdResult{}, err
+			return cmdOptions{}, err
 		}
 		options.command = parsed
 	}
 	if len(options.command) == 0 {
-		return CmdResult{}, errors.New("command cannot be empty")
+		return cmdOptions{}, errors.New("command cannot be empty")
 	}
+	return options, nil
+}
 
-	waitUntilDone := true
-	if options.wait != nil {
-		waitUntilDone = *options.wait
-	}
+// cmdContextRequest returns a request creating a CMD context that runs command
+// without waiting for it to complete.
+func cmdContextRequest(command []string, options cmdOptions) apispec.CreateContextRequest {
 	req := apispec.CreateContextRequest{
 		Type:          apispec.NewOptProcessType(apispec.ProcessTypeCmd),
-		Cmd:           apispec.NewOptCreateCMDContextRequest(apispec.CreateCMDContextRequest{Command: options.command}),
-		WaitUntilDone: apispec.NewOptBool(waitUntilDone),
+		Cmd:           apispec.NewOptCreateCMDContextRequest(apispec.CreateCMDContextRequest{Command: command}),
+		WaitUntilDone: apispec.NewOptBool(false),
 	}
 	if options.cwd != nil {
 		req.Cwd = apispec.NewOptString(*options.cwd)
@@ -240,48 +563,131 @@ func (s *Sandbox) Cmd(ctx context.Context, cmd string, opts ...CmdOption) (CmdRe
 	if options.ttlSec != nil {
 		req.TTLSec = apispec.NewOptInt32(*options.ttlSec)
 	}
-	contextResp, err := s.CreateContext(ctx, req)
-	if err != nil {
-		return CmdResult{}, err
-	}
-	if contextResp == nil {
-		return CmdResult{}, errors.New("create context returned nil response")
-	}
+	return req
+}
 
-	outputRaw := ""
-	if value, ok := contextResp.OutputRaw.Get(); ok {
-		outputRaw = value
+// runCmd runs a command to completion over the context WebSocket.
+func (s *Sandbox) runCmd(ctx context.Context, options cmdOptions) (CmdResult, error) {
+	cmd := s.remoteCmd(ctx, options)
+
+	// A single reader goroutine writes both streams, so the buffers need no locking.
+	stdout := newOutputBuffer(options.maxOutputBytes)
+	stderr := newOutputBuffer(options.maxOutputBytes)
+	combined := newOutputBuffer(options.maxOutputBytes)
+	var all io.Writer = combined
+	var sink *outputSink
+	if options.sink != nil {
+		sink = &outputSink{w: options.sink}
+		all = io.MultiWriter(combined, sink)
+	}
+	cmd.Stdout = io.MultiWriter(stdout, all)
+	cmd.Stderr = io.MultiWriter(stderr, all)
+	if options.stdout != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, options.stdout)
+	}
+	if options.stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, options.stderr)
+	}
+	if err := cmd.Start(); err != nil {
+		return CmdResult{}, err
 	}
+	err := cmd.Wait()
 
-	return CmdResult{
+	result := CmdResult{
 		SandboxID: s.ID,
-		ContextID: contextResp.ID,
-		OutputRaw: outputRaw,
-	}, nil
+		ContextID: cmd.ContextID,
+		OutputRaw: combined.String(),
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  cmd.ExitCode(),
+		StartedAt: cmd.StartedAt,
+		Duration:  time.Since(cmd.StartedAt),
+		Truncated: combined.Truncated(),
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = result.Stderr
+	}
+	if sinkErr := sink.Err(); sinkErr != nil {
+		err = errors.Join(err, sinkErr)
+	}
+	return result, err
 }
 
-func (s *Sandbox) ensureReplContext(ctx context.Context, language string, options runOptions) (string, error) {
-	if options.contextID != "" {
-		return options.contextID, nil
+// remoteCmd returns a RemoteCmd for options that keeps its context after Wait.
+func (s *Sandbox) remoteCmd(ctx context.Context, options cmdOptions) *RemoteCmd {
+	cmd := s.CommandContext(ctx, options.command[0], options.command[1:]...)
+	cmd.KeepContext = true
+	cmd.PTYSize = options.ptySize
+	cmd.ttlSec = options.ttlSec
+	cmd.idleTimeoutSec = options.idleTimeoutSec
+	if options.cwd != nil {
+		cmd.Dir = *options.cwd
 	}
+	if options.envVars != nil {
+		for key, value := range *options.envVars {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	cmd.ExitStatus = options.exitStatus != nil && *options.exitStatus
+	return cmd
+}
 
-	language = strings.TrimSpace(language)
-	if language == "" {
-		language = "python"
+// ensureReplContext returns the context for language and options, creating it
+// when it is not cached. It reports whether the context came from the cache.
+func (s *Sandbox) ensureReplContext(ctx context.Context, language string, options runOptions) (string, bool, error) {
+	if options.contextID != "" {
+		return options.contextID, false, nil
 	}
 
+	language = normalizeLanguage(language)
+	key := replCacheKey(language, options)
+
 	s.mu.Lock()
-	contextID := s.replContextByLang[language]
+	contextID := s.replContexts[key]
 	s.mu.Unlock()
 	if contextID != "" {
-		return contextID, nil
+		return contextID, true, nil
 	}
 
+	contextResp, err := s.CreateContext(ctx, replContextRequest(language, options))
+	if err != nil {
+		return "", false, err
+	}
+	if contextResp == nil {
+		return "", false, errors.New("create context returned nil response")
+	}
+
+	contextID = contextResp.ID
+	s.mu.Lock()
+	existing := s.replContexts[key]
+	if existing == "" {
+		s.replContexts[key] = contextID
+	}
+	s.mu.Unlock()
+	if existing != "" {
+		// A concurrent Run created the context first; use it and drop ours.
+		s.deleteContextQuietly(ctx, contextID)
+		return existing, false, nil
+	}
+	// Connecting before any input is sent keeps earlier output out of
+	// RunStream. Failures are left to RunStream, which connects on use.
+	_, _ = s.openReplStream(ctx, contextID)
+
+	return contextID, false, nil
+}
+
+// replContextRequest returns a request creating a REPL context for language.
+func replContextRequest(language string, options runOptions) apispec.CreateContextRequest {
+	repl := apispec.CreateREPLContextRequest{
+		Language: apispec.NewOptString(language),
+	}
+	if options.replConfig != nil {
+		repl.ReplConfig = apispec.NewOptREPLConfig(*options.replConfig)
+	}
 	req := apispec.CreateContextRequest{
 		Type: apispec.NewOptProcessType(apispec.ProcessTypeRepl),
-		Repl: apispec.NewOptCreateREPLContextRequest(apispec.CreateREPLContextRequest{
-			Language: apispec.NewOptString(language),
-		}),
+		Repl: apispec.NewOptCreateREPLContextRequest(repl),
 	}
 	if options.cwd != nil {
 		req.Cwd = apispec.NewOptString(*options.cwd)
@@ -298,20 +704,15 @@ func (s *Sandbox) ensureReplContext(ctx context.Context, language string, option
 	if options.ttlSec != nil {
 		req.TTLSec = apispec.NewOptInt32(*options.ttlSec)
 	}
-	contextResp, err := s.CreateContext(ctx, req)
-	if err != nil {
-		return "", err
-	}
-	if contextResp == nil {
-		return "", errors.New("create context returned nil response")
-	}
-
-	contextID = contextResp.ID
-	s.mu.Lock()
-	s.replContextByLang[language] = contextID
-	s.mu.Unlock()
+	return req
+}
 
-	return contextID, nil
+func normalizeLanguage(language string) string {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return "python"
+	}
+	return language
 }
 
 func parseCommand(input string) ([]string, error) {
@@ -0,0 +1,212 @@
+package sandbox0
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// DefaultDetachKeys is the detach sequence used by Attach: Ctrl-P followed by Ctrl-Q.
+var DefaultDetachKeys = []byte{0x10, 0x11}
+
+// ErrDetached is returned by Attach when the detach key sequence was read from Stdin.
+var ErrDetached = errors.New("sandbox0: detached from context")
+
+const keyInterrupt = 0x03
+
+// AttachOptions configures Sandbox.Attach.
+type AttachOptions struct {
+	// Stdin is forwarded to the process as input. Nil attaches output only.
+	Stdin io.Reader
+	// Stdout and Stderr receive output by WebSocket source. Nil discards it.
+	// Prompt output is written to Stdout.
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY puts Stdin into raw mode when it is a terminal, propagates the local
+	// window size to the context PTY and forwards Ctrl-C as an INT signal.
+	TTY bool
+	// DetachKeys ends the session without stopping the process.
+	// Nil uses DefaultDetachKeys; an empty non-nil slice disables detaching.
+	DetachKeys []byte
+}
+
+// Attach connects the local terminal to a running context, like docker attach.
+//
+// Attach returns nil when the process ends the stream, ErrDetached when the
+// detach keys are read, or ctx.Err() when ctx is done. The context keeps
+// running after Attach returns. A Stdin read that is blocked when Attach
+// returns is not interrupted; its data is discarded.
+func (s *Sandbox) Attach(ctx context.Context, contextID string, opts AttachOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.OpenStream(ctx, contextID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if opts.TTY {
+		if fd, ok := terminalFd(opts.Stdin); ok {
+			state, err := term.MakeRaw(fd)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = term.Restore(fd, state) }()
+		}
+		fd, ok := terminalFd(opts.Stdout)
+		if !ok {
+			fd, ok = terminalFd(opts.Stdin)
+		}
+		if ok {
+			var lastRows, lastCols int
+			resize := func() {
+				cols, rows, err := term.GetSize(fd)
+				if err != nil || (rows == lastRows && cols == lastCols) {
+					return
+				}
+				lastRows, lastCols = rows, cols
+				_ = stream.Resize(uint16(rows), uint16(cols))
+			}
+			resize()
+			go watchWindowSize(ctx, resize)
+		}
+	}
+
+	detached := make(chan struct{})
+	if opts.Stdin != nil {
+		detachKeys := opts.DetachKeys
+		if detachKeys == nil {
+			detachKeys = DefaultDetachKeys
+		}
+		filter := &attachKeyFilter{detach: detachKeys, interrupt: opts.TTY}
+		go func() {
+			if filter.copy(stream, opts.Stdin) {
+				close(detached)
+			}
+		}()
+	}
+
+	messages := make(chan error, 1)
+	go func() {
+		messages <- copyStreamOutput(stream, opts.Stdout, opts.Stderr)
+	}()
+
+	select {
+	case err := <-messages:
+		return err
+	case <-detached:
+		return ErrDetached
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// copyStreamOutput writes stream output to stdout and stderr until the stream ends.
+func copyStreamOutput(stream *ContextStream, stdout, stderr io.Writer) error {
+	stdout = writerOrDiscard(stdout)
+	stderr = writerOrDiscard(stderr)
+	for msg, err := range stream.Messages() {
+		if err != nil {
+			return err
+		}
+		output, ok := msg.(StreamOutput)
+		if !ok {
+			continue
+		}
+		dst := stdout
+		if output.Source == OutputSourceStderr {
+			dst = stderr
+		}
+		if _, err := io.WriteString(dst, output.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func terminalFd(v any) (int, bool) {
+	file, ok := v.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	fd := int(file.Fd())
+	return fd, term.IsTerminal(fd)
+}
+
+// attachKeyFilter scans keystrokes for the detach sequence and interrupts.
+// Bytes that may begin the detach sequence are held until it is resolved.
+type attachKeyFilter struct {
+	detach    []byte
+	interrupt bool
+	matched   int
+}
+
+// copy forwards in to stream and reports whether the detach sequence was read.
+func (f *attachKeyFilter) copy(stream *ContextStream, in io.Reader) bool {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			detached, sendErr := f.feed(buf[:n], func(data []byte) error {
+				_, err := stream.SendInput(string(data))
+				return err
+			}, stream.Signal)
+			if detached {
+				return true
+			}
+			if sendErr != nil {
+				return false
+			}
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
+func (f *attachKeyFilter) feed(p []byte, input func([]byte) error, signal func(string) error) (bool, error) {
+	out := make([]byte, 0, len(p))
+	flush := func() error {
+		if len(out) == 0 {
+			return nil
+		}
+		err := input(out)
+		out = out[:0]
+		return err
+	}
+	for _, b := range p {
+		if len(f.detach) > 0 {
+			if b == f.detach[f.matched] {
+				f.matched++
+				if f.matched == len(f.detach) {
+					f.matched = 0
+					return true, flush()
+				}
+				continue
+			}
+			if f.matched > 0 {
+				out = append(out, f.detach[:f.matched]...)
+				f.matched = 0
+				if b == f.detach[0] {
+					f.matched = 1
+					continue
+				}
+			}
+		}
+		if f.interrupt && b == keyInterrupt {
+			if err := flush(); err != nil {
+				return false, err
+			}
+			if err := signal("INT"); err != nil {
+				return false, err
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	return false, flush()
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package sandbox0
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchWindowSize calls resize on every SIGWINCH until ctx is done.
+func watchWindowSize(ctx context.Context, resize func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			resize()
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package sandbox0
+
+import (
+	"context"
+	"time"
+)
+
+// watchWindowSize polls for size changes until ctx is done; Windows has no SIGWINCH.
+func watchWindowSize(ctx context.Context, resize func()) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resize()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package sandbox0
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// defaultCancelGrace is how long a remote process gets to stop after each
+	// signal sent on cancellation before the next, stronger signal is sent.
+	defaultCancelGrace = 3 * time.Second
+	// signalTimeout bounds each signal request sent on cancellation.
+	signalTimeout = 5 * time.Second
+)
+
+// cancelSignals are sent in order to the remote process of a call whose
+// context was cancelled, each after the previous one had the grace period to
+// take effect.
+var cancelSignals = []string{"INT", "TERM", "KILL"}
+
+// cancelGrace returns the grace period between cancellation signals.
+func (c *Client) cancelGrace() time.Duration {
+	if c.cancelGracePeriod > 0 {
+		return c.cancelGracePeriod
+	}
+	return defaultCancelGrace
+}
+
+// WaitCancellations waits until the signals sent in the background for
+// cancelled calls are done, which is when each interrupted process stopped
+// or was sent KILL, or until ctx is done. Use it before exiting to make sure
+// no remote process outlives the program.
+func (c *Client) WaitCancellations(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.cancellations.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// interruptible runs call for the process in contextID. The call gets a
+// context that is not cancelled with ctx: when ctx is done first, interruptible
+// returns ctx.Err() at once and, in the background, sends the process INT,
+// then TERM, then KILL until call returns, so the remote process stops instead
+// of outliving the abandoned request. The background work is tracked for
+// Client.WaitCancellations.
+func (s *Sandbox) interruptible(ctx context.Context, contextID string, call func(context.Context) error) error {
+	if ctx.Done() == nil {
+		return call(ctx)
+	}
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan error, 1)
+	go func() {
+		done <- call(callCtx)
+	}()
+
+	select {
+	case err := <-done:
+		cancel()
+		return err
+	case <-ctx.Done():
+	}
+	s.client.cancellations.Add(1)
+	go func() {
+		defer s.client.cancellations.Done()
+		defer cancel()
+		s.escalate(callCtx, contextID, done)
+	}()
+	return ctx.Err()
+}
+
+// escalate sends the cancellation signals to the process in contextID, each
+// after the previous one had the grace period to take effect, until done
+// receives the result of the interrupted call. Signal errors other than a
+// context that is gone are passed to the cancel error handler.
+func (s *Sandbox) escalate(ctx context.Context, contextID string, done <-chan error) {
+	grace := s.client.cancelGrace()
+	for _, signal := range cancelSignals {
+		signalCtx, cancelSignal := context.WithTimeout(ctx, signalTimeout)
+		_, err := s.ContextSignal(signalCtx, contextID, signal)
+		cancelSignal()
+		if err != nil && !isNotFound(err) && s.client.onCancelError != nil {
+			s.client.onCancelError(contextID, fmt.Errorf("send %s: %w", signal, err))
+		}
+		timer := time.NewTimer(grace)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// deadlineTTL returns ttlSec, lowered to cover the deadline of ctx plus one
+// grace period per cancellation signal, three in all, so the server stops the
+// process even if the client goes away before it can signal it. Without a
+// deadline, or when ttlSec is already shorter, ttlSec is returned.
+func deadlineTTL(ctx context.Context, grace time.Duration, ttlSec *int32) *int32 {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ttlSec
+	}
+	remaining := time.Until(deadline) + time.Duration(len(cancelSignals))*grace
+	seconds := int32(min(math.Ceil(remaining.Seconds()), math.MaxInt32))
+	seconds = max(seconds, 1)
+	if ttlSec != nil && *ttlSec > 0 && *ttlSec <= seconds {
+		return ttlSec
+	}
+	return &seconds
+}
@@ -0,0 +1,450 @@
+package sandbox0
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+// RemoteCmd is a command running in a sandbox CMD context, modeled on os/exec.Cmd.
+// Output is streamed over the context WebSocket and routed by its source.
+//
+// A RemoteCmd cannot be reused after calling Start, Run, Output or CombinedOutput.
+type RemoteCmd struct {
+	// Path is the command to run.
+	Path string
+	// Args holds command line arguments, including the command as Args[0].
+	Args []string
+	// Env holds "KEY=value" entries added to the context environment.
+	Env []string
+	// Dir is the working directory. Empty uses the sandbox default.
+	Dir string
+
+	// Stdin is streamed to the process as input messages. When it reaches EOF
+	// and PTYSize is set, an end-of-transmission byte (Ctrl-D) is sent, which
+	// the terminal turns into end of input. Without a PTY the WebSocket
+	// protocol has no way to close the process input, so nothing is sent.
+	Stdin io.Reader
+	// Stdout and Stderr receive output by WebSocket source. Nil discards it.
+	// Prompt output is written to Stdout.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// PTYSize sets the terminal size of the context when non-nil.
+	PTYSize *apispec.PTYSize
+	// KeepContext leaves the context in place after Wait instead of deleting it.
+	KeepContext bool
+	// ExitStatus runs the command under /bin/sh so its exit status can be
+	// reported by servers that do not send one. The shell writes a status line
+	// to standard error, which is removed from the output. The image must
+	// provide a POSIX shell.
+	ExitStatus bool
+
+	// ContextID and StartedAt are set by Start.
+	ContextID string
+	StartedAt time.Time
+
+	sandbox        *Sandbox
+	ctx            context.Context
+	ttlSec         *int32
+	idleTimeoutSec *int32
+
+	mu         sync.Mutex
+	started    bool
+	conn       *contextConn
+	done       chan struct{}
+	readErr    error
+	closers    []io.Closer
+	outPipes   []io.Closer
+	stopCtx    func() bool
+	marker     string
+	exitCode   int
+	haveStatus bool
+	waitErr    error
+	waitOnce   sync.Once
+}
+
+// Command returns a RemoteCmd running name with args in the sandbox.
+func (s *Sandbox) Command(name string, args ...string) *RemoteCmd {
+	return s.CommandContext(context.Background(), name, args...)
+}
+
+// CommandContext is like Command but includes a context. If ctx is done
+// before the command completes, the remote process is sent INT, then TERM,
+// then KILL until it exits; see WithCancelGracePeriod. A ctx deadline also
+// limits the TTL of the context to the time left plus three grace periods,
+// so the server stops the process if the client goes away.
+func (s *Sandbox) CommandContext(ctx context.Context, name string, args ...string) *RemoteCmd {
+	if ctx == nil {
+		panic("sandbox0: nil Context")
+	}
+	return &RemoteCmd{
+		Path:    name,
+		Args:    append([]string{name}, args...),
+		sandbox: s,
+		ctx:     ctx,
+	}
+}
+
+func (c *RemoteCmd) argv() []string {
+	if len(c.Args) == 0 {
+		return []string{c.Path}
+	}
+	return append([]string{c.Path}, c.Args[1:]...)
+}
+
+// String returns a human-readable description of c.
+func (c *RemoteCmd) String() string {
+	return strings.Join(c.argv(), " ")
+}
+
+// Start starts the command without waiting for it to complete.
+func (c *RemoteCmd) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return errors.New("sandbox0: command already started")
+	}
+	if strings.TrimSpace(c.Path) == "" {
+		return errors.New("command cannot be empty")
+	}
+	c.started = true
+	ctx := c.ctx
+
+	command := c.argv()
+	if c.ExitStatus {
+		c.marker = newExitMarker()
+		command = wrapExitStatus(command, c.marker)
+	}
+	req := apispec.CreateContextRequest{
+		Type:          apispec.NewOptProcessType(apispec.ProcessTypeCmd),
+		Cmd:           apispec.NewOptCreateCMDContextRequest(apispec.CreateCMDContextRequest{Command: command}),
+		WaitUntilDone: apispec.NewOptBool(false),
+	}
+	if c.Dir != "" {
+		req.Cwd = apispec.NewOptString(c.Dir)
+	}
+	if len(c.Env) > 0 {
+		req.EnvVars = apispec.NewOptCreateContextRequestEnvVars(apispec.CreateContextRequestEnvVars(envListToMap(c.Env)))
+	}
+	if c.PTYSize != nil {
+		req.PtySize = apispec.NewOptPTYSize(*c.PTYSize)
+	}
+	if ttlSec := deadlineTTL(ctx, c.sandbox.client.cancelGrace(), c.ttlSec); ttlSec != nil {
+		req.TTLSec = apispec.NewOptInt32(*ttlSec)
+	}
+	if c.idleTimeoutSec != nil {
+		req.IdleTimeoutSec = apispec.NewOptInt32(*c.idleTimeoutSec)
+	}
+	c.StartedAt = time.Now()
+	contextResp, err := c.sandbox.CreateContext(ctx, req)
+	if err != nil {
+		c.closeDescriptors()
+		return err
+	}
+	if contextResp == nil {
+		c.closeDescriptors()
+		return errors.New("create context returned nil response")
+	}
+	c.ContextID = contextResp.ID
+
+	conn, _, err := c.sandbox.ConnectWSContext(ctx, c.ContextID)
+	if err != nil {
+		c.closeDescriptors()
+		c.deleteContext()
+		return err
+	}
+	c.conn = newContextConn(conn)
+	c.done = make(chan struct{})
+
+	go c.readLoop()
+	if c.Stdin != nil {
+		go c.copyStdin()
+	}
+	c.stopCtx = context.AfterFunc(ctx, c.interrupt)
+	return nil
+}
+
+// interrupt stops the process after ctx is done. It sends INT, then TERM,
+// then KILL, each after the previous signal had the grace period to take
+// effect, and closes the connection if the process is still running.
+func (c *RemoteCmd) interrupt() {
+	grace := c.sandbox.client.cancelGrace()
+	for _, signal := range cancelSignals {
+		if err := c.conn.send(ContextWebSocketRequest{Type: ContextMessageSignal, Signal: signal}); err != nil {
+			break
+		}
+		timer := time.NewTimer(grace)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	_ = c.conn.close()
+}
+
+func (c *RemoteCmd) readLoop() {
+	defer close(c.done)
+	defer func() {
+		for _, pipe := range c.outPipes {
+			_ = pipe.Close()
+		}
+	}()
+	stdout := writerOrDiscard(c.Stdout)
+	stderr := writerOrDiscard(c.Stderr)
+	var statusWriters []*exitStatusWriter
+	if c.marker != "" {
+		// With a PTY both streams arrive as stdout, so the marker is looked for in each.
+		stdoutStatus := newExitStatusWriter(stdout, c.marker)
+		stderrStatus := newExitStatusWriter(stderr, c.marker)
+		stdout, stderr = stdoutStatus, stderrStatus
+		statusWriters = []*exitStatusWriter{stderrStatus, stdoutStatus}
+	}
+	defer func() {
+		for _, status := range statusWriters {
+			if err := status.flush(); err != nil && c.readErr == nil {
+				c.readErr = err
+			}
+			if code, ok := status.exitCode(); ok && !c.haveStatus {
+				c.exitCode, c.haveStatus = code, true
+			}
+		}
+	}()
+	for {
+		msg, err := c.conn.read()
+		if err != nil {
+			if !isWSClosed(err) {
+				c.readErr = err
+			}
+			return
+		}
+		if msg.Type != ContextMessageOutput {
+			continue
+		}
+		var werr error
+		switch msg.Source {
+		case OutputSourceStderr:
+			_, werr = io.WriteString(stderr, msg.Data)
+		default:
+			_, werr = io.WriteString(stdout, msg.Data)
+		}
+		if werr != nil {
+			c.readErr = werr
+			return
+		}
+	}
+}
+
+func (c *RemoteCmd) copyStdin() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := c.Stdin.Read(buf)
+		if n > 0 {
+			if sendErr := c.conn.send(ContextWebSocketRequest{Type: ContextMessageInput, Data: string(buf[:n])}); sendErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && c.PTYSize != nil {
+				_ = c.conn.send(ContextWebSocketRequest{Type: ContextMessageInput, Data: "\x04"})
+			}
+			return
+		}
+	}
+}
+
+// Wait waits for the command to exit and for output copying to complete,
+// then deletes the context unless KeepContext is set.
+//
+// If the command ran and exited with a non-zero status, the error is of type *ExitError.
+func (c *RemoteCmd) Wait() error {
+	c.mu.Lock()
+	started := c.started && c.done != nil
+	c.mu.Unlock()
+	if !started {
+		return errors.New("sandbox0: command not started")
+	}
+	c.waitOnce.Do(func() {
+		<-c.done
+		killed := !c.stopCtx()
+		_ = c.conn.close()
+		c.closeDescriptors()
+		if !c.KeepContext {
+			c.deleteContext()
+		}
+		c.waitErr = c.readErr
+		if c.waitErr == nil && killed {
+			c.waitErr = c.ctx.Err()
+		}
+		if c.waitErr == nil && c.haveStatus && c.exitCode != 0 {
+			c.waitErr = &ExitError{
+				SandboxID: c.sandbox.ID,
+				ContextID: c.ContextID,
+				Command:   c.String(),
+				ExitCode:  c.exitCode,
+			}
+		}
+	})
+	return c.waitErr
+}
+
+// ExitCode returns the exit status of the exited command, or -1 if the command
+// has not exited or its status is unknown.
+func (c *RemoteCmd) ExitCode() int {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done == nil {
+		return -1
+	}
+	select {
+	case <-done:
+	default:
+		return -1
+	}
+	if !c.haveStatus {
+		return -1
+	}
+	return c.exitCode
+}
+
+// Run starts the command and waits for it to complete.
+func (c *RemoteCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output.
+// If the command exits with a non-zero status, the *ExitError carries its standard error
+// unless Stderr was set.
+func (c *RemoteCmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("sandbox0: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	var stderr bytes.Buffer
+	if c.Stderr == nil {
+		c.Stderr = &stderr
+	}
+	err := c.Run()
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.String()
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error.
+func (c *RemoteCmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("sandbox0: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("sandbox0: Stderr already set")
+	}
+	// Output is written by a single reader goroutine, so one buffer can back both writers.
+	var combined bytes.Buffer
+	c.Stdout = &combined
+	c.Stderr = &combined
+	err := c.Run()
+	return combined.Bytes(), err
+}
+
+// StdinPipe returns a pipe connected to the command's input when it starts.
+// Closing the pipe sends end-of-transmission to the process.
+func (c *RemoteCmd) StdinPipe() (io.WriteCloser, error) {
+	if c.Stdin != nil {
+		return nil, errors.New("sandbox0: Stdin already set")
+	}
+	if c.isStarted() {
+		return nil, errors.New("sandbox0: StdinPipe after process started")
+	}
+	pr, pw := io.Pipe()
+	c.Stdin = pr
+	c.closers = append(c.closers, pr)
+	return pw, nil
+}
+
+// StdoutPipe returns a pipe connected to the command's standard output.
+// The pipe is closed when the output stream ends, so read it to EOF before calling Wait.
+func (c *RemoteCmd) StdoutPipe() (io.ReadCloser, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("sandbox0: Stdout already set")
+	}
+	if c.isStarted() {
+		return nil, errors.New("sandbox0: StdoutPipe after process started")
+	}
+	pr, pw := io.Pipe()
+	c.Stdout = pw
+	c.outPipes = append(c.outPipes, pw)
+	return pr, nil
+}
+
+// StderrPipe returns a pipe connected to the command's standard error.
+// The pipe is closed when the output stream ends, so read it to EOF before calling Wait.
+func (c *RemoteCmd) StderrPipe() (io.ReadCloser, error) {
+	if c.Stderr != nil {
+		return nil, errors.New("sandbox0: Stderr already set")
+	}
+	if c.isStarted() {
+		return nil, errors.New("sandbox0: StderrPipe after process started")
+	}
+	pr, pw := io.Pipe()
+	c.Stderr = pw
+	c.outPipes = append(c.outPipes, pw)
+	return pr, nil
+}
+
+func (c *RemoteCmd) isStarted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+func (c *RemoteCmd) closeDescriptors() {
+	for _, closer := range c.closers {
+		_ = closer.Close()
+	}
+	c.closers = nil
+	if c.done == nil {
+		for _, pipe := range c.outPipes {
+			_ = pipe.Close()
+		}
+	}
+}
+
+func (c *RemoteCmd) deleteContext() {
+	if c.ContextID == "" {
+		return
+	}
+	c.sandbox.deleteContextQuietly(c.ctx, c.ContextID)
+}
+
+func envListToMap(env []string) map[string]string {
+	out := make(map[string]string, len(env))
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if key != "" {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
@@ -0,0 +1,281 @@
+package sandbox0
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+// SandboxConfigIssue describes a single sandbox configuration problem.
+type SandboxConfigIssue struct {
+	Field   string
+	Message string
+}
+
+// SandboxConfigError aggregates all validation problems found in a sandbox configuration.
+type SandboxConfigError struct {
+	Issues []SandboxConfigIssue
+}
+
+func (e *SandboxConfigError) Error() string {
+	if e == nil || len(e.Issues) == 0 {
+		return "invalid sandbox config"
+	}
+	parts := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		parts = append(parts, issue.Field+": "+issue.Message)
+	}
+	return "invalid sandbox config: " + strings.Join(parts, "; ")
+}
+
+func (e *SandboxConfigError) add(field, format string, args ...any) {
+	e.Issues = append(e.Issues, SandboxConfigIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateSandboxConfig checks a sandbox configuration for invalid values and
+// conflicting settings. It returns a *SandboxConfigError listing every problem found.
+func ValidateSandboxConfig(config apispec.SandboxConfig) error {
+	return validateSandboxConfig(config, false)
+}
+
+// validateSandboxConfig checks config. A partial config, as sent by
+// UpdateSandbox, only holds the fields that change, so checks that need a
+// field it may leave out, such as the webhook URL a new secret belongs to,
+// are skipped.
+func validateSandboxConfig(config apispec.SandboxConfig, partial bool) error {
+	verr := &SandboxConfigError{}
+
+	ttl, hasTTL := config.TTL.Get()
+	hardTTL, hasHardTTL := config.HardTTL.Get()
+	if hasTTL && ttl < 0 {
+		verr.add("ttl", "must not be negative, got %d", ttl)
+	}
+	if hasHardTTL && hardTTL < 0 {
+		verr.add("hard_ttl", "must not be negative, got %d", hardTTL)
+	}
+	if hasTTL && hasHardTTL && ttl > 0 && hardTTL > 0 && ttl > hardTTL {
+		verr.add("ttl", "must not exceed hard_ttl (%d > %d)", ttl, hardTTL)
+	}
+
+	if envVars, ok := config.EnvVars.Get(); ok {
+		for name := range envVars {
+			if strings.TrimSpace(name) == "" || strings.ContainsAny(name, "=\x00") {
+				verr.add("env_vars", "invalid variable name %q", name)
+			}
+		}
+	}
+
+	if webhook, ok := config.Webhook.Get(); ok {
+		rawURL := strings.TrimSpace(webhook.URL.Or(""))
+		switch {
+		case rawURL == "" && webhook.URL.Or("") != "":
+			verr.add("webhook.url", "must not be blank")
+		case rawURL == "":
+			if secret, ok := webhook.Secret.Get(); ok && secret != "" && !partial {
+				verr.add("webhook.url", "required when webhook secret is set")
+			}
+			if watchDir, ok := webhook.WatchDir.Get(); ok && watchDir != "" && !partial {
+				verr.add("webhook.url", "required when webhook watch_dir is set")
+			}
+		default:
+			parsed, err := url.Parse(rawURL)
+			if err != nil {
+				verr.add("webhook.url", "invalid URL: %v", err)
+			} else if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				verr.add("webhook.url", "must be an absolute http(s) URL, got %q", rawURL)
+			}
+		}
+		if watchDir, ok := webhook.WatchDir.Get(); ok && watchDir != "" && !strings.HasPrefix(watchDir, "/") {
+			verr.add("webhook.watch_dir", "must be an absolute path, got %q", watchDir)
+		}
+	}
+
+	seenPorts := map[int32]struct{}{}
+	for _, port := range config.ExposedPorts {
+		if port.Port < 1 || port.Port > 65535 {
+			verr.add("exposed_ports", "port %d out of range", port.Port)
+			continue
+		}
+		if _, dup := seenPorts[port.Port]; dup {
+			verr.add("exposed_ports", "port %d listed more than once", port.Port)
+		}
+		seenPorts[port.Port] = struct{}{}
+	}
+
+	if network, ok := config.Network.Get(); ok {
+		if err := network.Validate(); err != nil {
+			verr.add("network", "%v", err)
+		}
+	}
+
+	if len(verr.Issues) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// SandboxConfigBuilder builds a validated sandbox configuration.
+// Setters may be chained; all problems are reported together by Build.
+type SandboxConfigBuilder struct {
+	config apispec.SandboxConfig
+}
+
+// NewSandboxConfigBuilder returns an empty sandbox configuration builder.
+func NewSandboxConfigBuilder() *SandboxConfigBuilder {
+	return &SandboxConfigBuilder{}
+}
+
+// TTL sets the soft TTL in seconds.
+func (b *SandboxConfigBuilder) TTL(ttlSec int32) *SandboxConfigBuilder {
+	b.config.TTL = apispec.NewOptInt32(ttlSec)
+	return b
+}
+
+// HardTTL sets the hard TTL in seconds.
+func (b *SandboxConfigBuilder) HardTTL(ttlSec int32) *SandboxConfigBuilder {
+	b.config.HardTTL = apispec.NewOptInt32(ttlSec)
+	return b
+}
+
+// EnvVars merges environment variables into the configuration.
+func (b *SandboxConfigBuilder) EnvVars(envVars map[string]string) *SandboxConfigBuilder {
+	for name, value := range envVars {
+		b.EnvVar(name, value)
+	}
+	return b
+}
+
+// EnvVar sets a single environment variable.
+func (b *SandboxConfigBuilder) EnvVar(name, value string) *SandboxConfigBuilder {
+	envVars, _ := b.config.EnvVars.Get()
+	if envVars == nil {
+		envVars = apispec.SandboxConfigEnvVars{}
+	}
+	envVars[name] = value
+	b.config.EnvVars = apispec.NewOptSandboxConfigEnvVars(envVars)
+	return b
+}
+
+// Webhook sets the webhook URL and signing secret.
+func (b *SandboxConfigBuilder) Webhook(url, secret string) *SandboxConfigBuilder {
+	webhook, _ := b.config.Webhook.Get()
+	webhook.URL = apispec.NewOptString(url)
+	webhook.Secret = apispec.NewOptString(secret)
+	b.config.Webhook = apispec.NewOptWebhookConfig(webhook)
+	return b
+}
+
+// WebhookWatchDir sets the directory watched for file.modified webhook events.
+func (b *SandboxConfigBuilder) WebhookWatchDir(watchDir string) *SandboxConfigBuilder {
+	webhook, _ := b.config.Webhook.Get()
+	webhook.WatchDir = apispec.NewOptString(watchDir)
+	b.config.Webhook = apispec.NewOptWebhookConfig(webhook)
+	return b
+}
+
+// NetworkPolicy sets the sandbox network policy.
+func (b *SandboxConfigBuilder) NetworkPolicy(policy apispec.TplSandboxNetworkPolicy) *SandboxConfigBuilder {
+	b.config.Network = apispec.NewOptTplSandboxNetworkPolicy(policy)
+	return b
+}
+
+// AutoResume controls whether a paused sandbox resumes on access.
+func (b *SandboxConfigBuilder) AutoResume(enabled bool) *SandboxConfigBuilder {
+	b.config.AutoResume = apispec.NewOptBool(enabled)
+	return b
+}
+
+// ExposePort adds or updates an exposed port.
+func (b *SandboxConfigBuilder) ExposePort(port int32, resume bool) *SandboxConfigBuilder {
+	for i, existing := range b.config.ExposedPorts {
+		if existing.Port == port {
+			b.config.ExposedPorts[i].Resume = resume
+			return b
+		}
+	}
+	b.config.ExposedPorts = append(b.config.ExposedPorts, apispec.ExposedPortConfig{Port: port, Resume: resume})
+	return b
+}
+
+// Build validates and returns the configuration.
+func (b *SandboxConfigBuilder) Build() (apispec.SandboxConfig, error) {
+	config := cloneSandboxConfig(b.config)
+	if err := ValidateSandboxConfig(config); err != nil {
+		return apispec.SandboxConfig{}, err
+	}
+	return config, nil
+}
+
+// Option returns a SandboxOption applying the built configuration.
+// Validation happens when the option is used by ClaimSandbox.
+func (b *SandboxConfigBuilder) Option() SandboxOption {
+	return WithSandboxConfig(cloneSandboxConfig(b.config))
+}
+
+// DiffSandboxConfig returns a configuration containing only the fields of next
+// that differ from prev. Fields unset in next are left unset. The boolean
+// reports whether any field changed.
+func DiffSandboxConfig(prev, next apispec.SandboxConfig) (apispec.SandboxConfig, bool) {
+	var diff apispec.SandboxConfig
+	changed := false
+
+	if next.TTL.IsSet() && next.TTL != prev.TTL {
+		diff.TTL = next.TTL
+		changed = true
+	}
+	if next.HardTTL.IsSet() && next.HardTTL != prev.HardTTL {
+		diff.HardTTL = next.HardTTL
+		changed = true
+	}
+	if next.AutoResume.IsSet() && next.AutoResume != prev.AutoResume {
+		diff.AutoResume = next.AutoResume
+		changed = true
+	}
+	if next.EnvVars.IsSet() && !maps.Equal(next.EnvVars.Value, prev.EnvVars.Value) {
+		diff.EnvVars = next.EnvVars
+		changed = true
+	}
+	if next.Network.IsSet() && !reflect.DeepEqual(next.Network, prev.Network) {
+		diff.Network = next.Network
+		changed = true
+	}
+	if next.Webhook.IsSet() && next.Webhook != prev.Webhook {
+		diff.Webhook = next.Webhook
+		changed = true
+	}
+	if next.ExposedPorts != nil && !reflect.DeepEqual(next.ExposedPorts, prev.ExposedPorts) {
+		diff.ExposedPorts = next.ExposedPorts
+		changed = true
+	}
+	return diff, changed
+}
+
+// UpdateSandboxConfig validates next and sends only the fields that differ from prev.
+// When nothing changed, the current sandbox is returned without an update call.
+func (c *Client) UpdateSandboxConfig(ctx context.Context, sandboxID string, prev, next apispec.SandboxConfig) (*apispec.Sandbox, error) {
+	if err := ValidateSandboxConfig(next); err != nil {
+		return nil, err
+	}
+	diff, changed := DiffSandboxConfig(prev, next)
+	if !changed {
+		return c.GetSandbox(ctx, sandboxID)
+	}
+	return c.UpdateSandbox(ctx, sandboxID, apispec.SandboxUpdateRequest{
+		Config: apispec.NewOptSandboxConfig(diff),
+	})
+}
+
+func cloneSandboxConfig(config apispec.SandboxConfig) apispec.SandboxConfig {
+	if envVars, ok := config.EnvVars.Get(); ok {
+		config.EnvVars = apispec.NewOptSandboxConfigEnvVars(maps.Clone(envVars))
+	}
+	if config.ExposedPorts != nil {
+		config.ExposedPorts = append([]apispec.ExposedPortConfig(nil), config.ExposedPorts...)
+	}
+	return config
+}
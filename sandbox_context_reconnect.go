@@ -0,0 +1,470 @@
+package sandbox0
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultStreamBackoff is the wait before the first reconnect attempt when
+	// WithStreamReconnect is given no positive backoff.
+	defaultStreamBackoff = 500 * time.Millisecond
+	// streamMaxBackoff caps the wait between reconnect attempts.
+	streamMaxBackoff = 30 * time.Second
+	// streamQueueLimit bounds the inputs queued while a stream reconnects.
+	streamQueueLimit = 256
+	// streamWrittenLimit bounds the written inputs tracked until their
+	// StreamDone arrives; the oldest are forgotten first, as raw keystrokes
+	// may never be answered.
+	streamWrittenLimit = 256
+	// streamResumeTail is how much of the latest output is kept to find where
+	// the output replayed after a reconnect continues it.
+	streamResumeTail = 4 << 10
+	// streamResumeQuiet and streamResumeLimit bound how long replayed output
+	// is held after a reconnect while it settles.
+	streamResumeQuiet = 100 * time.Millisecond
+	streamResumeLimit = 2 * time.Second
+)
+
+// StreamState is the connection state of a ContextStream.
+type StreamState string
+
+const (
+	StreamConnected    StreamState = "connected"
+	StreamReconnecting StreamState = "reconnecting"
+	StreamClosed       StreamState = "closed"
+)
+
+// StreamStateEvent reports a change of the connection state of a stream.
+type StreamStateEvent struct {
+	State StreamState
+	// Attempt counts the reconnect attempts since the connection dropped,
+	// for reconnecting events.
+	Attempt int
+	// Err is the error that dropped the connection or failed the previous
+	// attempt for reconnecting events, and the error that ended the stream
+	// for the closed event.
+	Err error
+}
+
+// StreamGap marks where messages may be missing. On a stream opened with
+// WithStreamReconnect it is delivered when output produced while the stream
+// was disconnected could not be recovered from what the server replayed. It
+// also replaces messages dropped because Messages was not read fast enough.
+type StreamGap struct {
+	// Err is the error that dropped the connection, or ErrStreamBacklogFull.
+	Err error
+}
+
+// StreamInputUnconfirmed marks an input on a stream opened with
+// WithStreamReconnect that was written before the connection dropped, but
+// whose StreamDone did not arrive. The process may or may not have read it;
+// it is not sent again.
+type StreamInputUnconfirmed struct {
+	RequestID string
+	// Err is the error that dropped the connection.
+	Err error
+}
+
+// ErrInputUnconfirmed is returned by ExecStream and RunStream when the
+// connection dropped after the input was written but before its StreamDone
+// arrived.
+var ErrInputUnconfirmed = errors.New("sandbox0: connection dropped before the input was confirmed")
+
+// errInputQueueFull is returned when too many inputs are queued while a
+// stream reconnects.
+var errInputQueueFull = errors.New("sandbox0: too many inputs queued while the stream reconnects")
+
+func (StreamGap) streamMessage()              {}
+func (StreamInputUnconfirmed) streamMessage() {}
+
+type streamOptions struct {
+	pingInterval time.Duration
+	pongWait     time.Duration
+	reconnect    bool
+	attempts     int
+	backoff      time.Duration
+	onState      func(StreamStateEvent)
+	// maxOutputBytes limits the output ExecStream keeps.
+	maxOutputBytes int
+}
+
+// StreamOption configures OpenStream.
+type StreamOption func(*streamOptions)
+
+// WithStreamKeepalive sets how often the stream pings the server and how long
+// it waits for any frame before treating the connection as dead. Defaults are
+// 30 and 75 seconds; lower them behind load balancers with a shorter idle
+// timeout. Durations that are not positive keep the default.
+func WithStreamKeepalive(interval, timeout time.Duration) StreamOption {
+	return func(opts *streamOptions) {
+		if interval > 0 {
+			opts.pingInterval = interval
+		}
+		if timeout > 0 {
+			opts.pongWait = timeout
+		}
+	}
+}
+
+// WithStreamReconnect makes the stream reconnect when the WebSocket drops,
+// such as on a load balancer idle timeout or when the sandbox is resumed,
+// instead of ending. It waits backoff, or 500ms if backoff is not positive,
+// before the first attempt and doubles the wait after each failed one, up to
+// 30 seconds. The stream ends after attempts failed attempts in a row, or
+// only when the context is gone if attempts is not positive.
+//
+// After a reconnect, the output the server replays is matched against the
+// output already received so that only new output is delivered. Where they do
+// not line up, a StreamGap is delivered before the replayed output. Input sent
+// while the stream is reconnecting, or whose write failed, is queued and sent
+// once the replay settled. Input that was written before the connection
+// dropped is not sent again, since the process may already have read it: if
+// its StreamDone is not replayed either, a StreamInputUnconfirmed is
+// delivered, and ExecStream returns ErrInputUnconfirmed. Resize and Signal
+// fail while the stream is reconnecting.
+func WithStreamReconnect(attempts int, backoff time.Duration) StreamOption {
+	return func(opts *streamOptions) {
+		opts.reconnect = true
+		opts.attempts = attempts
+		opts.backoff = backoff
+	}
+}
+
+// WithStreamStateHandler calls fn when the stream connects, starts a reconnect
+// attempt and closes. Calls are serialized, so fn needs no locking. fn may
+// close the stream, for example to give up reconnecting, but must not block:
+// messages are not read while it runs.
+func WithStreamStateHandler(fn func(StreamStateEvent)) StreamOption {
+	return func(opts *streamOptions) {
+		opts.onState = fn
+	}
+}
+
+// WithStreamMaxOutputBytes limits the output kept in the RunResult of
+// ExecStream as WithMaxOutputBytes does for Run. Messages is not affected.
+func WithStreamMaxOutputBytes(n int) StreamOption {
+	return func(opts *streamOptions) {
+		opts.maxOutputBytes = n
+	}
+}
+
+// State returns the connection state of the stream.
+func (s *ContextStream) State() StreamState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// streamReplay holds the messages received on a new connection while the
+// replay settles.
+type streamReplay struct {
+	cause    error
+	messages []ContextWebSocketResponse
+}
+
+// emit calls the state handler with event. Events emitted while the handler
+// runs, such as by a handler that closes the stream, are queued and passed on
+// in order once it returns.
+func (s *ContextStream) emit(event StreamStateEvent) {
+	if s.options.onState == nil {
+		return
+	}
+	s.stateMu.Lock()
+	s.events = append(s.events, event)
+	if s.emitting {
+		s.stateMu.Unlock()
+		return
+	}
+	s.emitting = true
+	for len(s.events) > 0 {
+		event := s.events[0]
+		s.events = s.events[1:]
+		s.stateMu.Unlock()
+		s.options.onState(event)
+		s.stateMu.Lock()
+	}
+	s.emitting = false
+	s.stateMu.Unlock()
+}
+
+// sendInput sends input with requestID. On a reconnecting stream the input is
+// queued while the stream is not connected or its replay has not settled,
+// and tracked once written until its StreamDone arrives.
+func (s *ContextStream) sendInput(requestID, data string) error {
+	msg := ContextWebSocketRequest{Type: ContextMessageInput, Data: data, RequestID: requestID}
+	if !s.options.reconnect {
+		return s.send(msg)
+	}
+
+	// inputMu keeps inputs in order with the queued ones sent by settle.
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+	s.mu.Lock()
+	if s.finished {
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+	if s.state != StreamConnected || s.replay != nil {
+		defer s.mu.Unlock()
+		if len(s.queued) >= streamQueueLimit {
+			return errInputQueueFull
+		}
+		s.queued = append(s.queued, msg)
+		return nil
+	}
+	conn := s.conn
+	s.track(requestID)
+	s.mu.Unlock()
+	if err := conn.send(msg); err != nil {
+		// The connection dropped before the input was written. The read loop
+		// reconnects and settle sends it then.
+		s.mu.Lock()
+		s.acknowledge(requestID)
+		s.queued = append(s.queued, msg)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// track records that the input sent with requestID is about to be written.
+// It must be called with s.mu held.
+func (s *ContextStream) track(requestID string) {
+	if len(s.written) >= streamWrittenLimit {
+		s.written = s.written[1:]
+	}
+	s.written = append(s.written, requestID)
+}
+
+// acknowledge forgets the written input sent with requestID. It must be
+// called with s.mu held.
+func (s *ContextStream) acknowledge(requestID string) {
+	if i := slices.Index(s.written, requestID); i >= 0 {
+		s.written = slices.Delete(s.written, i, i+1)
+	}
+}
+
+// reportUnconfirmed delivers a StreamInputUnconfirmed for each input written
+// to a connection that dropped with cause before its StreamDone arrived, and
+// fails the ExecStream calls waiting for them. It must be called with s.mu
+// held.
+func (s *ContextStream) reportUnconfirmed(cause error) {
+	for _, requestID := range s.written {
+		if exec, ok := s.execs[requestID]; ok {
+			exec.failed <- fmt.Errorf("%w: %w", ErrInputUnconfirmed, cause)
+			delete(s.execs, requestID)
+		}
+		s.push(StreamInputUnconfirmed{RequestID: requestID, Err: cause})
+	}
+	s.written = nil
+}
+
+// recordOutput keeps the latest output for aligning a replay. It must be
+// called with s.mu held.
+func (s *ContextStream) recordOutput(data string) {
+	s.outputBytes += len(data)
+	s.tail = append(s.tail, data...)
+	if len(s.tail) > 2*streamResumeTail {
+		s.tail = append(s.tail[:0], s.tail[len(s.tail)-streamResumeTail:]...)
+	}
+}
+
+// isServerClose reports whether the server closed the WebSocket on purpose,
+// such as when the process exited, so a reconnect would not resume anything.
+func isServerClose(err error) bool {
+	return websocket.IsCloseError(err, websocket.CloseNormalClosure)
+}
+
+// reconnect dials the context WebSocket again with backoff after the
+// connection dropped with cause, and returns the new connection.
+func (s *ContextStream) reconnect(cause error) (*contextConn, error) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return nil, ErrStreamClosed
+	}
+	s.state = StreamReconnecting
+	s.mu.Unlock()
+
+	backoff := s.options.backoff
+	if backoff <= 0 {
+		backoff = defaultStreamBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		s.emit(StreamStateEvent{State: StreamReconnecting, Attempt: attempt, Err: cause})
+		if !sleepContext(s.dialCtx, backoff) {
+			return nil, ErrStreamClosed
+		}
+		ws, resp, err := s.sandbox.ConnectWSContext(s.dialCtx, s.ContextID)
+		if err == nil {
+			return s.resume(newContextConn(ws), cause)
+		}
+		if s.dialCtx.Err() != nil {
+			return nil, ErrStreamClosed
+		}
+		if resp != nil && !retryableDialStatus(resp.StatusCode) {
+			// The context is gone or access was revoked.
+			if apiErr := handleErrorResponse(s.dialCtx, resp); apiErr != nil {
+				return nil, apiErr
+			}
+		}
+		if s.options.attempts > 0 && attempt >= s.options.attempts {
+			return nil, fmt.Errorf("reconnect context stream: gave up after %d attempts: %w", attempt, err)
+		}
+		cause = err
+		backoff = min(2*backoff, streamMaxBackoff)
+	}
+}
+
+func retryableDialStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// resume switches the stream to conn and holds the messages the server
+// replays to it until they settle.
+func (s *ContextStream) resume(conn *contextConn, cause error) (*contextConn, error) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		_ = conn.conn.Close()
+		return nil, ErrStreamClosed
+	}
+	old := s.conn
+	s.conn = conn
+	s.state = StreamConnected
+	replay := &streamReplay{cause: cause}
+	if s.replay != nil && s.replay.cause != nil {
+		// The previous connection dropped before its replay settled.
+		replay.cause = s.replay.cause
+	}
+	s.replay = replay
+	s.mu.Unlock()
+
+	_ = old.conn.Close()
+	s.watch(conn)
+	s.emit(StreamStateEvent{State: StreamConnected})
+	go s.settle(replay)
+	return conn, nil
+}
+
+// settle waits until no message has arrived for streamResumeQuiet, up to
+// streamResumeLimit, then delivers the new part of the replay, reports the
+// written inputs that were not confirmed and sends the queued ones.
+func (s *ContextStream) settle(replay *streamReplay) {
+	deadline := time.Now().Add(streamResumeLimit)
+	for {
+		s.mu.Lock()
+		received := s.received
+		s.mu.Unlock()
+		timer := time.NewTimer(streamResumeQuiet)
+		select {
+		case <-timer.C:
+		case <-s.closed:
+			timer.Stop()
+			return
+		}
+		s.mu.Lock()
+		settled := s.received == received || time.Now().After(deadline)
+		s.mu.Unlock()
+		if settled {
+			break
+		}
+	}
+
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+	s.mu.Lock()
+	if s.replay != replay {
+		// Closed, or superseded by another reconnect.
+		s.mu.Unlock()
+		return
+	}
+	s.replay = nil
+	s.deliverReplay(replay)
+	s.reportUnconfirmed(replay.cause)
+	conn := s.conn
+	s.mu.Unlock()
+	s.wake()
+
+	for {
+		s.mu.Lock()
+		if len(s.queued) == 0 || s.conn != conn || s.finished {
+			s.mu.Unlock()
+			return
+		}
+		msg := s.queued[0]
+		s.queued = s.queued[1:]
+		s.track(msg.RequestID)
+		s.mu.Unlock()
+		if conn.send(msg) != nil {
+			// Sent by the settle of the next connection.
+			s.mu.Lock()
+			s.acknowledge(msg.RequestID)
+			s.queued = append([]ContextWebSocketRequest{msg}, s.queued...)
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// deliverReplay delivers the replayed output that follows the output already
+// received, with a StreamGap first if the two do not line up, and the
+// completions of inputs still in flight. It must be called with s.mu held.
+func (s *ContextStream) deliverReplay(replay *streamReplay) {
+	var replayed strings.Builder
+	for _, msg := range replay.messages {
+		if msg.Type == ContextMessageOutput {
+			replayed.WriteString(msg.Data)
+		}
+	}
+	tail := s.tail[max(0, len(s.tail)-streamResumeTail):]
+	skip, ok := alignReplay(string(tail), s.outputBytes, replayed.String())
+	if !ok {
+		s.push(StreamGap{Err: replay.cause})
+	}
+	for _, msg := range replay.messages {
+		switch msg.Type {
+		case ContextMessageOutput:
+			if skip >= len(msg.Data) {
+				skip -= len(msg.Data)
+				continue
+			}
+			msg.Data = msg.Data[skip:]
+			skip = 0
+			s.deliver(msg)
+		case ContextMessageDone:
+			// Completions of earlier inputs are replayed too; only those of
+			// inputs in flight are new.
+			if _, ok := s.execs[msg.RequestID]; ok || slices.Contains(s.written, msg.RequestID) {
+				s.deliver(msg)
+			}
+		}
+	}
+}
+
+// alignReplay returns the offset in replayed, the output the server replayed
+// to a new connection, at which output that was not received before starts.
+// tail is the latest output received and total the size of all of it. ok is
+// false if replayed does not contain tail, in which case output may be
+// missing before replayed.
+func alignReplay(tail string, total int, replayed string) (int, bool) {
+	if tail == "" {
+		return 0, true
+	}
+	// While the server buffer has not wrapped, the replay starts with all the
+	// output received so far.
+	if total <= len(replayed) && replayed[total-len(tail):total] == tail {
+		return total, true
+	}
+	if i := strings.LastIndex(replayed, tail); i >= 0 {
+		return i + len(tail), true
+	}
+	return 0, false
+}
@@ -0,0 +1,515 @@
+package sandbox0
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"iter"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sandbox0-ai/sdk-go/pkg/keys"
+)
+
+const (
+	// streamPingInterval is how often ContextStream sends keepalive pings.
+	streamPingInterval = 30 * time.Second
+	// streamPongWait is how long ContextStream waits for any frame before
+	// treating the connection as dead.
+	streamPongWait = 75 * time.Second
+	// streamBacklog bounds messages buffered for Messages while nobody reads them.
+	streamBacklog = 4096
+)
+
+var (
+	// ErrStreamClosed is returned by ContextStream methods after Close.
+	ErrStreamClosed = errors.New("sandbox0: context stream closed")
+	// ErrStreamBacklogFull is the Err of the StreamGap that replaces messages
+	// dropped because Messages was not read fast enough.
+	ErrStreamBacklogFull = errors.New("sandbox0: context stream backlog full")
+)
+
+// StreamMessage is a typed server message on a ContextStream.
+// It is StreamOutput, StreamDone, StreamGap or, on a stream opened with
+// WithStreamReconnect, StreamInputUnconfirmed.
+type StreamMessage interface {
+	streamMessage()
+}
+
+// StreamOutput is process output received on a ContextStream.
+type StreamOutput struct {
+	// Source is OutputSourceStdout, OutputSourceStderr or OutputSourcePrompt.
+	Source string
+	Data   string
+}
+
+// StreamDone marks the completion of the input sent with RequestID.
+type StreamDone struct {
+	RequestID string
+}
+
+func (StreamOutput) streamMessage() {}
+func (StreamDone) streamMessage()   {}
+
+// ContextStream is a typed client for the context WebSocket. It pings the
+// server to keep the connection alive and, when opened with
+// WithStreamReconnect, reconnects when the connection drops.
+// All methods are safe for concurrent use.
+type ContextStream struct {
+	SandboxID string
+	ContextID string
+
+	sandbox *Sandbox
+	options streamOptions
+	stop    func() bool
+	closed  chan struct{}
+	execMu  sync.Mutex
+	inputMu sync.Mutex
+	// stateMu guards the state events queued for the state handler.
+	stateMu  sync.Mutex
+	events   []StreamStateEvent
+	emitting bool
+	// dialCtx is canceled by Close to stop reconnect attempts.
+	dialCtx    context.Context
+	cancelDial context.CancelFunc
+
+	mu       sync.Mutex
+	conn     *contextConn
+	state    StreamState
+	backlog  []StreamMessage
+	notify   chan struct{}
+	execs    map[string]*streamExec
+	received int
+	err      error
+	finished bool
+	once     sync.Once
+
+	// Reconnect state, guarded by mu: inputs queued until the stream is
+	// connected, request IDs of written inputs awaiting their StreamDone, the
+	// latest output with the total output size, and the messages replayed
+	// after a reconnect while they settle.
+	queued      []ContextWebSocketRequest
+	written     []string
+	tail        []byte
+	outputBytes int
+	replay      *streamReplay
+}
+
+type streamExec struct {
+	stdout *outputBuffer
+	stderr *outputBuffer
+	raw    *outputBuffer
+	done   chan StreamDone
+	failed chan error
+}
+
+// OpenStream connects to the WebSocket of a context and returns a typed stream.
+// The stream is closed when ctx is done or Close is called. By default the
+// stream ends when the connection drops; see WithStreamReconnect.
+func (s *Sandbox) OpenStream(ctx context.Context, contextID string, opts ...StreamOption) (*ContextStream, error) {
+	if strings.TrimSpace(contextID) == "" {
+		return nil, errors.New("context ID cannot be empty")
+	}
+	options := streamOptions{pingInterval: streamPingInterval, pongWait: streamPongWait}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	conn, _, err := s.ConnectWSContext(ctx, contextID)
+	if err != nil {
+		return nil, err
+	}
+	stream := &ContextStream{
+		SandboxID: s.ID,
+		ContextID: contextID,
+		sandbox:   s,
+		options:   options,
+		conn:      newContextConn(conn),
+		state:     StreamConnected,
+		closed:    make(chan struct{}),
+		notify:    make(chan struct{}, 1),
+		execs:     make(map[string]*streamExec),
+	}
+	stream.dialCtx, stream.cancelDial = context.WithCancel(context.WithoutCancel(ctx))
+	stream.watch(stream.conn)
+	stream.emit(StreamStateEvent{State: StreamConnected})
+	stop := context.AfterFunc(ctx, func() {
+		_ = stream.Close()
+	})
+	stream.mu.Lock()
+	stream.stop = stop
+	stream.mu.Unlock()
+	go stream.readLoop()
+	go stream.keepalive()
+	return stream, nil
+}
+
+// SendInput writes data to the process and returns the request ID that the
+// server echoes in the matching StreamDone message. On a stream opened with
+// WithStreamReconnect, input sent while the stream is reconnecting is queued
+// and sent once it is connected again.
+func (s *ContextStream) SendInput(data string) (string, error) {
+	requestID := newRequestID()
+	if err := s.sendInput(requestID, data); err != nil {
+		return "", err
+	}
+	return requestID, nil
+}
+
+// SendKeys writes key presses to the process, encoded as an xterm would send
+// them, and returns the request ID like SendInput. See the keys package.
+func (s *ContextStream) SendKeys(input ...keys.Input) (string, error) {
+	if len(input) == 0 {
+		return "", errors.New("keys cannot be empty")
+	}
+	return s.SendInput(keys.Encode(input...))
+}
+
+// Resize changes the PTY size of the context.
+func (s *ContextStream) Resize(rows, cols uint16) error {
+	return s.send(ContextWebSocketRequest{Type: ContextMessageResize, Rows: int32(rows), Cols: int32(cols)})
+}
+
+// Signal sends a signal such as "INT" or "TERM" to the process.
+func (s *ContextStream) Signal(signal string) error {
+	if strings.TrimSpace(signal) == "" {
+		return errors.New("signal cannot be empty")
+	}
+	return s.send(ContextWebSocketRequest{Type: ContextMessageSignal, Signal: signal})
+}
+
+// ExecStream sends input and waits for its StreamDone, returning the output
+// received in between, limited by WithStreamMaxOutputBytes. Output is not
+// tagged with a request ID, so concurrent ExecStream calls on one stream run
+// one at a time.
+func (s *ContextStream) ExecStream(ctx context.Context, input string) (RunResult, error) {
+	s.execMu.Lock()
+	defer s.execMu.Unlock()
+
+	requestID := newRequestID()
+	limit := s.options.maxOutputBytes
+	exec := &streamExec{
+		stdout: newOutputBuffer(limit),
+		stderr: newOutputBuffer(limit),
+		raw:    newOutputBuffer(limit),
+		done:   make(chan StreamDone, 1),
+		failed: make(chan error, 1),
+	}
+	s.mu.Lock()
+	if s.finished {
+		err := s.err
+		s.mu.Unlock()
+		return RunResult{}, err
+	}
+	s.execs[requestID] = exec
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.execs, requestID)
+		s.mu.Unlock()
+	}()
+
+	startedAt := time.Now()
+	if err := s.sendInput(requestID, input); err != nil {
+		return RunResult{}, err
+	}
+
+	select {
+	case <-exec.done:
+	case err := <-exec.failed:
+		return RunResult{}, err
+	case <-s.closed:
+		s.mu.Lock()
+		err := s.err
+		s.mu.Unlock()
+		return RunResult{}, err
+	case <-ctx.Done():
+		return RunResult{}, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := RunResult{
+		SandboxID: s.SandboxID,
+		ContextID: s.ContextID,
+		OutputRaw: exec.raw.String(),
+		Stdout:    exec.stdout.String(),
+		Stderr:    exec.stderr.String(),
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+		Truncated: exec.raw.Truncated(),
+	}
+	return result, nil
+}
+
+// Messages returns an iterator over messages received on the stream.
+// Messages that arrive before iteration starts are buffered and delivered
+// first; when the buffer overflows the oldest messages are replaced by a
+// StreamGap with ErrStreamBacklogFull. Concurrent iterators share one queue, so each message
+// is yielded to only one of them. Iteration ends when the stream closes;
+// a non-nil error is yielded if it closed abnormally.
+func (s *ContextStream) Messages() iter.Seq2[StreamMessage, error] {
+	return func(yield func(StreamMessage, error) bool) {
+		for {
+			msg, err := s.next(context.Background())
+			if err != nil {
+				if !errors.Is(err, ErrStreamClosed) {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(msg, nil) {
+				// Hand any remaining messages to another iterator.
+				s.wake()
+				return
+			}
+		}
+	}
+}
+
+// next removes and returns the oldest buffered message, waiting until one
+// arrives, the stream ends or ctx is done. After the stream ends it returns
+// the error that closed it, or ErrStreamClosed.
+func (s *ContextStream) next(ctx context.Context) (StreamMessage, error) {
+	for {
+		s.mu.Lock()
+		if len(s.backlog) > 0 {
+			msg := s.backlog[0]
+			s.backlog[0] = nil
+			s.backlog = s.backlog[1:]
+			s.mu.Unlock()
+			return msg, nil
+		}
+		if s.finished {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				err = ErrStreamClosed
+			}
+			return nil, err
+		}
+		s.mu.Unlock()
+		select {
+		case <-s.notify:
+		case <-s.closed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// discardBacklog drops the messages received but not yet read.
+func (s *ContextStream) discardBacklog() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.backlog)
+	s.backlog = s.backlog[:0]
+}
+
+// ended reports whether the stream has ended.
+func (s *ContextStream) ended() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error that closed the stream, or nil while it is open.
+func (s *ContextStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Done returns a channel that is closed when the stream ends.
+func (s *ContextStream) Done() <-chan struct{} {
+	return s.closed
+}
+
+// Close closes the WebSocket. The context itself is left running.
+func (s *ContextStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		if conn := s.end(ErrStreamClosed); conn != nil {
+			err = conn.close()
+		}
+	})
+	return err
+}
+
+// current returns the connection in use, which changes on reconnect.
+func (s *ContextStream) current() *contextConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn
+}
+
+func (s *ContextStream) send(msg ContextWebSocketRequest) error {
+	select {
+	case <-s.closed:
+		return s.Err()
+	default:
+	}
+	return s.current().send(msg)
+}
+
+// watch sets the read deadline of conn and extends it whenever a pong arrives.
+func (s *ContextStream) watch(conn *contextConn) {
+	pongWait := s.options.pongWait
+	_ = conn.conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.conn.SetPongHandler(func(string) error {
+		return conn.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+func (s *ContextStream) readLoop() {
+	conn := s.current()
+	for {
+		msg, err := conn.read()
+		if err == nil {
+			_ = conn.conn.SetReadDeadline(time.Now().Add(s.options.pongWait))
+			s.dispatch(msg)
+			continue
+		}
+		if s.options.reconnect && !isServerClose(err) {
+			if conn, err = s.reconnect(err); err == nil {
+				continue
+			}
+		}
+		if isWSClosed(err) {
+			err = ErrStreamClosed
+		}
+		s.finish(err)
+		return
+	}
+}
+
+func (s *ContextStream) dispatch(msg ContextWebSocketResponse) {
+	s.mu.Lock()
+	s.received++
+	if s.replay != nil {
+		// Replayed messages are held until they settle; see resume.
+		s.replay.messages = append(s.replay.messages, msg)
+		s.mu.Unlock()
+		return
+	}
+	s.deliver(msg)
+	s.mu.Unlock()
+	s.wake()
+}
+
+// deliver feeds msg to running ExecStream calls and the backlog. It must be
+// called with s.mu held.
+func (s *ContextStream) deliver(msg ContextWebSocketResponse) {
+	var typed StreamMessage
+	switch msg.Type {
+	case ContextMessageOutput:
+		typed = StreamOutput{Source: msg.Source, Data: msg.Data}
+		if s.options.reconnect {
+			s.recordOutput(msg.Data)
+		}
+		for _, exec := range s.execs {
+			_, _ = exec.raw.WriteString(msg.Data)
+			if msg.Source == OutputSourceStderr {
+				_, _ = exec.stderr.WriteString(msg.Data)
+			} else {
+				_, _ = exec.stdout.WriteString(msg.Data)
+			}
+		}
+	case ContextMessageDone:
+		done := StreamDone{RequestID: msg.RequestID}
+		typed = done
+		if exec, ok := s.execs[msg.RequestID]; ok {
+			exec.done <- done
+			delete(s.execs, msg.RequestID)
+		}
+		s.acknowledge(msg.RequestID)
+	default:
+		return
+	}
+	s.push(typed)
+}
+
+// push appends msg to the backlog. When the backlog is full the oldest
+// message is dropped and the head of the backlog becomes a StreamGap, so the
+// reader learns that messages are missing. It must be called with s.mu held.
+func (s *ContextStream) push(msg StreamMessage) {
+	if len(s.backlog) >= streamBacklog {
+		if gap, ok := s.backlog[0].(StreamGap); ok && errors.Is(gap.Err, ErrStreamBacklogFull) {
+			copy(s.backlog[1:], s.backlog[2:])
+			s.backlog[len(s.backlog)-1] = nil
+			s.backlog = s.backlog[:len(s.backlog)-1]
+		} else {
+			s.backlog[0] = StreamGap{Err: ErrStreamBacklogFull}
+		}
+	}
+	s.backlog = append(s.backlog, msg)
+}
+
+// keepalive pings the server so idle connections are not dropped and dead
+// ones are detected by the read deadline. On a reconnecting stream a failed
+// ping is left to the read loop, which reconnects.
+func (s *ContextStream) keepalive() {
+	ticker := time.NewTicker(s.options.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(10 * time.Second)
+			if err := s.current().conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil && !s.options.reconnect {
+				return
+			}
+		}
+	}
+}
+
+// finish ends the stream with err and closes its connection.
+func (s *ContextStream) finish(err error) {
+	if conn := s.end(err); conn != nil {
+		_ = conn.conn.Close()
+	}
+}
+
+// end marks the stream finished with err and returns its connection for the
+// caller to close, or nil when the stream had already ended.
+func (s *ContextStream) end(err error) *contextConn {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return nil
+	}
+	s.finished = true
+	s.err = err
+	s.state = StreamClosed
+	s.replay = nil
+	close(s.closed)
+	conn, stop := s.conn, s.stop
+	s.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+	s.cancelDial()
+	s.emit(StreamStateEvent{State: StreamClosed, Err: err})
+	s.wake()
+	return conn
+}
+
+// wake signals a waiting Messages iterator.
+func (s *ContextStream) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func newRequestID() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return "req-" + hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,91 @@
+package sandbox0
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Context WebSocket message types.
+const (
+	ContextMessageInput  = "input"
+	ContextMessageResize = "resize"
+	ContextMessageSignal = "signal"
+	ContextMessageOutput = "output"
+	ContextMessageDone   = "done"
+)
+
+// Context WebSocket output sources.
+const (
+	OutputSourceStdout = "stdout"
+	OutputSourceStderr = "stderr"
+	OutputSourcePrompt = "prompt"
+)
+
+// ContextWebSocketRequest is a client message on the context WebSocket.
+type ContextWebSocketRequest struct {
+	Type      string `json:"type"`
+	Data      string `json:"data,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Rows      int32  `json:"rows,omitempty"`
+	Cols      int32  `json:"cols,omitempty"`
+	Signal    string `json:"signal,omitempty"`
+}
+
+// ContextWebSocketResponse is a server message on the context WebSocket.
+type ContextWebSocketResponse struct {
+	Type      string `json:"type"`
+	Source    string `json:"source,omitempty"`
+	Data      string `json:"data,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// contextConn serializes writes on a context WebSocket.
+// gorilla/websocket allows one concurrent reader and one concurrent writer.
+type contextConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func newContextConn(conn *websocket.Conn) *contextConn {
+	return &contextConn{conn: conn}
+}
+
+func (c *contextConn) send(msg ContextWebSocketRequest) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *contextConn) read() (ContextWebSocketResponse, error) {
+	var msg ContextWebSocketResponse
+	err := c.conn.ReadJSON(&msg)
+	return msg, err
+}
+
+func (c *contextConn) close() error {
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+// isWSClosed reports whether err marks the normal end of a WebSocket stream:
+// a close frame from the server, or a read on a connection closed locally.
+// A connection that drops without a close frame is not a normal end.
+func isWSClosed(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+	"github.com/sandbox0-ai/sdk-go/pkg/keys"
 )
 
 // ListContext returns all contexts for a sandbox.
@@ -99,19 +100,42 @@ func (s *Sandbox) ContextInput(ctx context.Context, contextID string, input stri
 	return resp, nil
 }
 
+// SendKeys sends key presses to a context, encoded as an xterm would send
+// them. Use it to drive terminal programs in a context with a PTY; see the
+// keys package.
+func (s *Sandbox) SendKeys(ctx context.Context, contextID string, input ...keys.Input) (*apispec.SuccessWrittenResponse, error) {
+	if len(input) == 0 {
+		return nil, errors.New("keys cannot be empty")
+	}
+	return s.ContextInput(ctx, contextID, keys.Encode(input...))
+}
+
 // ContextExec sends input and waits for completion.
+//
+// If ctx is done before the execution completes, ContextExec returns
+// ctx.Err() at once and the process is sent INT, then TERM, then KILL in the
+// background until it stops; see WithCancelGracePeriod and
+// Client.WaitCancellations.
 func (s *Sandbox) ContextExec(ctx context.Context, contextID string, input string) (*apispec.ContextExecResponse, error) {
-	resp, err := s.client.api.APIV1SandboxesIDContextsCtxIDExecPost(ctx, &apispec.ContextInputRequest{Data: input}, apispec.APIV1SandboxesIDContextsCtxIDExecPostParams{
-		ID:    s.ID,
-		CtxID: contextID,
+	var data apispec.ContextExecResponse
+	err := s.interruptible(ctx, contextID, func(ctx context.Context) error {
+		resp, err := s.client.api.APIV1SandboxesIDContextsCtxIDExecPost(ctx, &apispec.ContextInputRequest{Data: input}, apispec.APIV1SandboxesIDContextsCtxIDExecPostParams{
+			ID:    s.ID,
+			CtxID: contextID,
+		})
+		if err != nil {
+			return err
+		}
+		var ok bool
+		data, ok = resp.Data.Get()
+		if !ok {
+			return unexpectedResponseError(resp)
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	data, ok := resp.Data.Get()
-	if !ok {
-		return nil, unexpectedResponseError(resp)
-	}
 	return &data, nil
 }
 
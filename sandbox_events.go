@@ -0,0 +1,372 @@
+package sandbox0
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/sandbox0webhook"
+)
+
+// SandboxEventType identifies a sandbox event delivered by Events.
+type SandboxEventType string
+
+// Sandbox event types. Process and file types match the webhook event names.
+const (
+	SandboxEventStatusChanged  SandboxEventType = "sandbox.status_changed"
+	SandboxEventPaused         SandboxEventType = "sandbox.paused"
+	SandboxEventResumed        SandboxEventType = "sandbox.resumed"
+	SandboxEventProcessStarted SandboxEventType = "process.started"
+	SandboxEventProcessExited  SandboxEventType = "process.exited"
+	SandboxEventProcessCrashed SandboxEventType = "process.crashed"
+	SandboxEventFileModified   SandboxEventType = "file.modified"
+	// SandboxEventError reports a non-fatal error from one of the sources.
+	SandboxEventError SandboxEventType = "error"
+)
+
+// SandboxEventSource identifies the mechanism that produced an event.
+type SandboxEventSource string
+
+// Sandbox event sources.
+const (
+	SandboxEventSourceWebhook   SandboxEventSource = "webhook"
+	SandboxEventSourceFileWatch SandboxEventSource = "file_watch"
+	SandboxEventSourcePoll      SandboxEventSource = "poll"
+)
+
+// SandboxEvent is a lifecycle, process or file event for one sandbox.
+type SandboxEvent struct {
+	Type      SandboxEventType
+	Source    SandboxEventSource
+	SandboxID string
+	// Timestamp is the server event time for webhooks and the observation time otherwise.
+	Timestamp time.Time
+
+	// Status is set for status changes.
+	Status string
+	// ContextID is set for process events.
+	ContextID string
+	// ExitCode is set for process exits when known. Polling cannot observe exit codes.
+	ExitCode *int
+	// Path and FileEvent are set for file events.
+	Path      string
+	FileEvent string
+	// Err is set for SandboxEventError.
+	Err error
+}
+
+// EventFilter selects the sources and types of events returned by Events.
+type EventFilter struct {
+	// Types limits delivered events. Empty means all types. SandboxEventError
+	// events are always delivered so source failures are not hidden.
+	Types []SandboxEventType
+	// WatchPath enables file events from the file watch WebSocket.
+	WatchPath      string
+	WatchRecursive bool
+	// Webhook supplies webhook events for this sandbox. Sandbox and process
+	// state is still polled, since the broker drops events arriving while the
+	// subscription buffer is full and webhooks report no status changes. A
+	// pause, resume, process start or process exit seen by both sources is
+	// delivered once, by the first source to see it.
+	Webhook *sandbox0webhook.Broker
+	// PollInterval is the polling period. Default is 2s.
+	PollInterval time.Duration
+}
+
+func (f EventFilter) allows(eventType SandboxEventType) bool {
+	return len(f.Types) == 0 || eventType == SandboxEventError || slices.Contains(f.Types, eventType)
+}
+
+const defaultEventPollInterval = 2 * time.Second
+
+// Events returns a single channel merging webhook, file watch and polling
+// events for the sandbox. The channel is closed when ctx is done.
+func (s *Sandbox) Events(ctx context.Context, filter EventFilter) (<-chan SandboxEvent, error) {
+	out := make(chan SandboxEvent, 16)
+	emit := func(event SandboxEvent) bool {
+		if !filter.allows(event.Type) {
+			return true
+		}
+		event.SandboxID = s.ID
+		select {
+		case out <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var sources []func()
+	if filter.WatchPath != "" {
+		events, errs, unsubscribe, err := s.WatchFiles(ctx, filter.WatchPath, filter.WatchRecursive)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, func() {
+			defer func() { _ = unsubscribe() }()
+			s.forwardFileWatch(ctx, events, errs, emit)
+		})
+	}
+	state := &eventState{running: map[string]bool{}}
+	if filter.Webhook != nil {
+		events, unsubscribe := filter.Webhook.Subscribe(s.ID, 16)
+		sources = append(sources, func() {
+			defer unsubscribe()
+			forwardWebhook(ctx, events, state, emit)
+		})
+	}
+	interval := filter.PollInterval
+	if interval <= 0 {
+		interval = defaultEventPollInterval
+	}
+	sources = append(sources, func() {
+		s.pollEvents(ctx, interval, state, emit)
+	})
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			source()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
+
+func (s *Sandbox) forwardFileWatch(ctx context.Context, events <-chan FileWatchResponse, errs <-chan error, emit func(SandboxEvent) bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !emit(SandboxEvent{Type: SandboxEventError, Source: SandboxEventSourceFileWatch, Timestamp: time.Now(), Err: err}) {
+				return
+			}
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			if msg.Type != "event" {
+				continue
+			}
+			if !emit(SandboxEvent{
+				Type:      SandboxEventFileModified,
+				Source:    SandboxEventSourceFileWatch,
+				Timestamp: time.Now(),
+				Path:      msg.Path,
+				FileEvent: msg.Event,
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// eventState is the sandbox and process state last reported by Events. It is
+// shared by the webhook and polling sources so that a change seen by both is
+// delivered once.
+type eventState struct {
+	mu      sync.Mutex
+	paused  bool
+	known   bool
+	running map[string]bool
+}
+
+// setPaused records the pause state and reports whether it changed.
+func (st *eventState) setPaused(paused bool) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	changed := !st.known || st.paused != paused
+	st.paused, st.known = paused, true
+	return changed
+}
+
+// setRunning records whether the process of a context is running and
+// returns the previous state, if known.
+func (st *eventState) setRunning(contextID string, running bool) (bool, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	wasRunning, known := st.running[contextID]
+	st.running[contextID] = running
+	return wasRunning, known
+}
+
+// forget drops a context and reports whether its process was running.
+func (st *eventState) forget(contextID string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	wasRunning := st.running[contextID]
+	delete(st.running, contextID)
+	return wasRunning
+}
+
+// isNew records the change reported by a webhook event and reports whether
+// the event is new. Events that do not change the state are always new.
+func (st *eventState) isNew(event SandboxEvent) bool {
+	switch event.Type {
+	case SandboxEventPaused, SandboxEventResumed:
+		return st.setPaused(event.Type == SandboxEventPaused)
+	case SandboxEventProcessStarted:
+		wasRunning, known := st.setRunning(event.ContextID, true)
+		return !known || !wasRunning
+	case SandboxEventProcessExited, SandboxEventProcessCrashed:
+		wasRunning, known := st.setRunning(event.ContextID, false)
+		return !known || wasRunning
+	}
+	return true
+}
+
+func forwardWebhook(ctx context.Context, events <-chan sandbox0webhook.Event, state *eventState, emit func(SandboxEvent) bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			converted, ok := sandboxEventFromWebhook(event)
+			if !ok || !state.isNew(converted) {
+				continue
+			}
+			if !emit(converted) {
+				return
+			}
+		}
+	}
+}
+
+func sandboxEventFromWebhook(event sandbox0webhook.Event) (SandboxEvent, bool) {
+	meta := event.Metadata()
+	converted := SandboxEvent{
+		Source:    SandboxEventSourceWebhook,
+		Timestamp: meta.Timestamp,
+	}
+	switch e := event.(type) {
+	case *sandbox0webhook.SandboxPausedEvent:
+		converted.Type = SandboxEventPaused
+	case *sandbox0webhook.SandboxResumedEvent:
+		converted.Type = SandboxEventResumed
+	case *sandbox0webhook.ProcessStartedEvent:
+		converted.Type = SandboxEventProcessStarted
+		converted.ContextID = e.ContextID
+	case *sandbox0webhook.ProcessExitedEvent:
+		converted.Type = SandboxEventProcessExited
+		converted.ContextID = e.ContextID
+		converted.ExitCode = e.ExitCode
+	case *sandbox0webhook.ProcessCrashedEvent:
+		converted.Type = SandboxEventProcessCrashed
+		converted.ContextID = e.ContextID
+		converted.ExitCode = e.ExitCode
+	case *sandbox0webhook.FileModifiedEvent:
+		converted.Type = SandboxEventFileModified
+		converted.Path = e.Path
+		converted.FileEvent = e.Event
+	default:
+		return SandboxEvent{}, false
+	}
+	if converted.Timestamp.IsZero() {
+		converted.Timestamp = time.Now()
+	}
+	return converted, true
+}
+
+// pollEvents derives lifecycle and process events by diffing sandbox and
+// context state between polls. Contexts are not listed while the sandbox is
+// paused so that polling does not auto resume it. Changes already reported
+// by the webhook source are not reported again.
+func (s *Sandbox) pollEvents(ctx context.Context, interval time.Duration, state *eventState, emit func(SandboxEvent) bool) {
+	var (
+		initialized bool
+		lastStatus  string
+		// listed holds the contexts in the last context list.
+		listed = map[string]bool{}
+	)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		sandbox, err := s.client.GetSandbox(ctx, s.ID)
+		if err != nil {
+			if ctx.Err() != nil || !emit(SandboxEvent{Type: SandboxEventError, Source: SandboxEventSourcePoll, Timestamp: now, Err: err}) {
+				return
+			}
+		} else {
+			if initialized && sandbox.Status != lastStatus {
+				if !emit(SandboxEvent{Type: SandboxEventStatusChanged, Source: SandboxEventSourcePoll, Timestamp: now, Status: sandbox.Status}) {
+					return
+				}
+			}
+			if state.setPaused(sandbox.Paused) && initialized {
+				eventType := SandboxEventResumed
+				if sandbox.Paused {
+					eventType = SandboxEventPaused
+				}
+				if !emit(SandboxEvent{Type: eventType, Source: SandboxEventSourcePoll, Timestamp: now, Status: sandbox.Status}) {
+					return
+				}
+			}
+			lastStatus = sandbox.Status
+
+			if !sandbox.Paused {
+				if !s.pollContexts(ctx, now, initialized, listed, state, emit) {
+					return
+				}
+			}
+			initialized = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sandbox) pollContexts(ctx context.Context, now time.Time, notify bool, listed map[string]bool, state *eventState, emit func(SandboxEvent) bool) bool {
+	contexts, err := s.ListContext(ctx)
+	if err != nil {
+		return ctx.Err() == nil && emit(SandboxEvent{Type: SandboxEventError, Source: SandboxEventSourcePoll, Timestamp: now, Err: err})
+	}
+	seen := make(map[string]bool, len(contexts))
+	for _, c := range contexts {
+		seen[c.ID] = true
+		wasRunning, known := state.setRunning(c.ID, c.Running)
+		if !notify {
+			continue
+		}
+		switch {
+		case c.Running && (!known || !wasRunning):
+			if !emit(SandboxEvent{Type: SandboxEventProcessStarted, Source: SandboxEventSourcePoll, Timestamp: now, ContextID: c.ID}) {
+				return false
+			}
+		case !c.Running && known && wasRunning:
+			if !emit(SandboxEvent{Type: SandboxEventProcessExited, Source: SandboxEventSourcePoll, Timestamp: now, ContextID: c.ID}) {
+				return false
+			}
+		}
+	}
+	for id := range listed {
+		if seen[id] {
+			continue
+		}
+		delete(listed, id)
+		if state.forget(id) && notify {
+			if !emit(SandboxEvent{Type: SandboxEventProcessExited, Source: SandboxEventSourcePoll, Timestamp: now, ContextID: id}) {
+				return false
+			}
+		}
+	}
+	maps.Copy(listed, seen)
+	return true
+}
@@ -0,0 +1,100 @@
+package sandbox0
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrShellSyntax is wrapped by the error Cmd returns for a command that uses
+// shell syntax, which Cmd would otherwise pass to the program as literal text.
+var ErrShellSyntax = errors.New("sandbox0: command uses shell syntax")
+
+// shellOperators are the words a shell reads as pipes, command lists,
+// background jobs and redirections, but Cmd passes to the program as literal
+// arguments. Characters inside words, as in a URL query, a glob or code
+// passed to an interpreter, are not checked: a program may expect them.
+var shellOperators = map[string]bool{
+	"|": true, "||": true, "&": true, "&&": true, ";": true,
+	"<": true, ">": true, ">>": true, "2>": true, "2>>": true, "&>": true, "2>&1": true,
+}
+
+// Shell returns the argv that runs script with /bin/sh -c, for use with Exec,
+// so pipes, redirections, variables and globs in script are interpreted by
+// the shell. Quote untrusted values with ShellQuote before adding them to
+// script.
+func Shell(script string) []string {
+	return []string{"/bin/sh", "-c", script}
+}
+
+// ShellQuote quotes each of args for a POSIX shell and joins them with
+// spaces, so a shell expands the result back into exactly args. Arguments made
+// only of safe characters are left unquoted.
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@%+=:,./-", r))
+	}) < 0
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// checkShellSyntax reports shell operators written as separate words outside
+// quotes in a command passed to Cmd, such as "a | b" or "make && make
+// install". Quoted text is passed on as is, so sh -c 'a | b' is accepted.
+func checkShellSyntax(cmd string) error {
+	var quote rune
+	escaped, comment := false, false
+	var word strings.Builder
+	literal := false
+	endWord := func() error {
+		op := word.String()
+		word.Reset()
+		if !literal && shellOperators[op] {
+			return fmt.Errorf("%w: %q is not interpreted by Cmd; use Exec with Shell to run a shell command line", ErrShellSyntax, op)
+		}
+		literal = false
+		return nil
+	}
+	for _, r := range cmd {
+		switch {
+		case comment:
+			// Comments run to the end of the line, as in a shell.
+			comment = r != '\n'
+		case escaped:
+			escaped = false
+			word.WriteRune(r)
+		case r == '\\' && quote != '\'':
+			escaped, literal = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, literal = r, true
+		case strings.ContainsRune(" \t\r\n", r):
+			if err := endWord(); err != nil {
+				return err
+			}
+		case r == '#' && word.Len() == 0 && !literal:
+			comment = true
+		default:
+			word.WriteRune(r)
+		}
+	}
+	return endWord()
+}
@@ -0,0 +1,127 @@
+package sandbox0
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"strconv"
+)
+
+// exitStatusShell runs wrapped commands so their exit status can be reported.
+// The context API does not return the exit status of a CMD process, so when
+// it is asked for, the command is run under a shell that prints a unique
+// marker followed by the status to standard error once the command returns.
+const exitStatusShell = "/bin/sh"
+
+func newExitMarker() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return "__sandbox0_exit_" + hex.EncodeToString(buf[:]) + "__"
+}
+
+// wrapExitStatus returns argv wrapped so the exit status is printed after marker.
+// The marker only contains [a-z0-9_], so it is safe inside single quotes.
+func wrapExitStatus(argv []string, marker string) []string {
+	script := `"$@"; s0_status=$?; printf '%s%d\n' '` + marker + `' "$s0_status" >&2; exit "$s0_status"`
+	return append([]string{exitStatusShell, "-c", script, "sh"}, argv...)
+}
+
+// exitStatusWriter forwards output to w while removing the exit status line,
+// the marker followed by the status and a newline. Bytes that could be the
+// start of the marker are held back until the next write resolves them or
+// flush is called. Output after the status line, such as from background
+// children of the command, is forwarded.
+type exitStatusWriter struct {
+	w       io.Writer
+	marker  []byte
+	pending []byte
+	found   bool
+	status  []byte
+	ended   bool
+}
+
+func newExitStatusWriter(w io.Writer, marker string) *exitStatusWriter {
+	return &exitStatusWriter{w: w, marker: []byte(marker)}
+}
+
+func (f *exitStatusWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if f.ended {
+		return n, f.forward(p)
+	}
+	if f.found {
+		return n, f.readStatus(p)
+	}
+	data := append(f.pending, p...)
+	f.pending = nil
+	if i := bytes.Index(data, f.marker); i >= 0 {
+		f.found = true
+		if err := f.forward(data[:i]); err != nil {
+			return n, err
+		}
+		return n, f.readStatus(data[i+len(f.marker):])
+	}
+	keep := markerPrefixSuffix(data, f.marker)
+	f.pending = append([]byte(nil), data[len(data)-keep:]...)
+	return n, f.forward(data[:len(data)-keep])
+}
+
+// readStatus collects the status up to the end of its line and forwards
+// whatever follows it.
+func (f *exitStatusWriter) readStatus(p []byte) error {
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		f.status = append(f.status, p...)
+		return nil
+	}
+	f.status = append(f.status, p[:i]...)
+	f.ended = true
+	return f.forward(p[i+1:])
+}
+
+func (f *exitStatusWriter) forward(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := f.w.Write(p)
+	return err
+}
+
+// flush writes any held back bytes when the stream ended without the marker.
+func (f *exitStatusWriter) flush() error {
+	if f.found || len(f.pending) == 0 {
+		return nil
+	}
+	pending := f.pending
+	f.pending = nil
+	return f.forward(pending)
+}
+
+// exitCode returns the reported exit status, if the marker was seen.
+func (f *exitStatusWriter) exitCode() (int, bool) {
+	if !f.found {
+		return 0, false
+	}
+	end := 0
+	for end < len(f.status) && f.status[end] >= '0' && f.status[end] <= '9' {
+		end++
+	}
+	code, err := strconv.Atoi(string(f.status[:end]))
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// markerPrefixSuffix returns the length of the longest suffix of data that is
+// a proper prefix of marker.
+func markerPrefixSuffix(data, marker []byte) int {
+	limit := min(len(data), len(marker)-1)
+	for size := limit; size > 0; size-- {
+		if bytes.HasPrefix(marker, data[len(data)-size:]) {
+			return size
+		}
+	}
+	return 0
+}
@@ -0,0 +1,592 @@
+package sandbox0
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+	"github.com/sandbox0-ai/sdk-go/pkg/output"
+)
+
+const (
+	// jobLogLimit bounds the output a Job retains for Tail and Follow.
+	jobLogLimit = 1 << 20
+	// jobPollInterval is how often a Job polls its context when the
+	// WebSocket drops before the command exits.
+	jobPollInterval = time.Second
+)
+
+// exitMarkerRe matches the exit status marker of commands started by another
+// client, whose marker is not known in advance.
+var exitMarkerRe = regexp.MustCompile(`__sandbox0_exit_[0-9a-f]{16}__`)
+
+// JobState is the state of a Job.
+type JobState string
+
+const (
+	JobRunning JobState = "running"
+	JobPaused  JobState = "paused"
+	JobExited  JobState = "exited"
+)
+
+// JobStatus reports the state of a Job.
+type JobStatus struct {
+	State JobState
+	// ExitCode is the exit status of an exited job, or -1 while the job is
+	// running or when the status is unknown.
+	ExitCode int
+}
+
+// ErrJobClosed is returned by Job methods that wait for the command after
+// Close was called.
+var ErrJobClosed = errors.New("sandbox0: job closed")
+
+// JobOption configures StartJob. Every CmdOption is also a JobOption.
+type JobOption interface {
+	applyJob(*jobOptions)
+}
+
+type jobOptions struct {
+	cmd        []CmdOption
+	autoDelete bool
+}
+
+type jobOptionFunc func(*jobOptions)
+
+func (f jobOptionFunc) applyJob(opts *jobOptions) {
+	f(opts)
+}
+
+func (o CmdOption) applyJob(opts *jobOptions) {
+	opts.cmd = append(opts.cmd, o)
+}
+
+// WithJobAutoDelete makes a job started by StartJob delete its context once
+// the command exits.
+func WithJobAutoDelete() JobOption {
+	return jobOptionFunc(func(opts *jobOptions) {
+		opts.autoDelete = true
+	})
+}
+
+// Job is a command running in the background in a CMD context.
+// Its output is read over the context WebSocket and retained, up to a limit,
+// for Tail and Follow. All methods are safe for concurrent use.
+type Job struct {
+	SandboxID string
+	ContextID string
+	// Command is the argv of a job started by StartJob. It is nil for jobs
+	// discovered by Jobs.
+	Command   []string
+	StartedAt time.Time
+
+	sandbox    *Sandbox
+	autoDelete bool
+	// stopCtx is canceled by Close to stop reading output and polling.
+	stopCtx context.Context
+	stop    context.CancelFunc
+
+	mu         sync.Mutex
+	attached   bool
+	marker     string
+	chunks     []OutputChunk
+	base       int
+	size       int
+	changed    chan struct{}
+	done       chan struct{}
+	finished   bool
+	exitCode   int
+	haveStatus bool
+	err        error
+}
+
+// StartJob starts cmd in a new CMD context and returns without waiting for it.
+// It accepts the options of Cmd, of which WithCmdWait is ignored, and
+// WithJobAutoDelete. With WithJobAutoDelete the context is deleted once the
+// command exits, and with WithCmdExitStatus(true) Wait and Status report its
+// exit status.
+//
+// The job keeps running after ctx is done; use Kill to stop it. Output is read
+// in the background until the command exits; use Close to stop earlier.
+func (s *Sandbox) StartJob(ctx context.Context, cmd string, opts ...JobOption) (*Job, error) {
+	jobOpts := jobOptions{}
+	for _, opt := range opts {
+		opt.applyJob(&jobOpts)
+	}
+	options, err := parseCmdOptions(cmd, jobOpts.cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	command := options.command
+	var marker string
+	if options.exitStatus != nil && *options.exitStatus {
+		marker = newExitMarker()
+		command = wrapExitStatus(command, marker)
+	}
+	startedAt := time.Now()
+	contextResp, err := s.CreateContext(ctx, cmdContextRequest(command, options))
+	if err != nil {
+		return nil, err
+	}
+	if contextResp == nil {
+		return nil, errors.New("create context returned nil response")
+	}
+
+	job := s.newJob(contextResp.ID, startedAt)
+	job.Command = options.command
+	job.marker = marker
+	job.autoDelete = jobOpts.autoDelete
+	if err := job.attach(ctx); err != nil {
+		s.deleteContextQuietly(ctx, job.ContextID)
+		return nil, err
+	}
+	s.mu.Lock()
+	s.jobs[job.ContextID] = job
+	s.mu.Unlock()
+	return job, nil
+}
+
+// Jobs lists the jobs started by StartJob on this Sandbox together with the
+// other CMD contexts of the sandbox, such as daemons started by another
+// client. Jobs whose context no longer exists are dropped. The result is
+// ordered by start time.
+//
+// Output of discovered jobs is read from the output the server replays when
+// it is first requested; their exit status is reported when the command was
+// started by this SDK with exit status reporting.
+func (s *Sandbox) Jobs(ctx context.Context) ([]*Job, error) {
+	contexts, err := s.ListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	present := make(map[string]bool, len(contexts))
+	var jobs []*Job
+	for _, info := range contexts {
+		if info.Type != apispec.ProcessTypeCmd {
+			continue
+		}
+		present[info.ID] = true
+		job, ok := s.jobs[info.ID]
+		if !ok {
+			startedAt, _ := time.Parse(time.RFC3339Nano, info.CreatedAt)
+			job = s.newJob(info.ID, startedAt)
+			s.jobs[info.ID] = job
+		}
+		jobs = append(jobs, job)
+	}
+	for contextID := range s.jobs {
+		if !present[contextID] {
+			delete(s.jobs, contextID)
+		}
+	}
+	slices.SortStableFunc(jobs, func(a, b *Job) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return jobs, nil
+}
+
+func (s *Sandbox) newJob(contextID string, startedAt time.Time) *Job {
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &Job{
+		SandboxID: s.ID,
+		ContextID: contextID,
+		StartedAt: startedAt,
+		sandbox:   s,
+		stopCtx:   stopCtx,
+		stop:      stop,
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+		exitCode:  -1,
+	}
+}
+
+// Status returns the current state of the job.
+func (j *Job) Status(ctx context.Context) (JobStatus, error) {
+	info, err := j.sandbox.GetContext(ctx, j.ContextID)
+	if err != nil {
+		j.mu.Lock()
+		finished, exitCode := j.finished, j.exitCode
+		j.mu.Unlock()
+		if isNotFound(err) && finished {
+			return JobStatus{State: JobExited, ExitCode: exitCode}, nil
+		}
+		return JobStatus{}, err
+	}
+	switch {
+	case info.Paused:
+		return JobStatus{State: JobPaused, ExitCode: -1}, nil
+	case info.Running:
+		return JobStatus{State: JobRunning, ExitCode: -1}, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	exitCode := -1
+	if j.finished {
+		exitCode = j.exitCode
+	}
+	return JobStatus{State: JobExited, ExitCode: exitCode}, nil
+}
+
+// Wait waits for the job to exit.
+// If the command exited with a non-zero status, the error is of type *ExitError.
+func (j *Job) Wait(ctx context.Context) error {
+	if err := j.attach(ctx); err != nil {
+		return err
+	}
+	select {
+	case <-j.done:
+		return j.result()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the job has exited and its
+// output has been read. It is only closed once output is being read, which
+// happens on StartJob or the first call to Wait, Tail or Follow.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Tail returns the last n lines of retained output, rendered through a
+// terminal screen model. A negative n returns every retained line.
+//
+// Output is read from StartJob on. For a job found with Jobs, it is read
+// from the first call to Wait, Tail or Follow, and the output produced before
+// then arrives as the server replays it: a Tail right after that call may
+// return only part of it. Use Follow or Wait first to read all of it.
+func (j *Job) Tail(ctx context.Context, n int) ([]string, error) {
+	if err := j.attach(ctx); err != nil {
+		return nil, err
+	}
+	var raw strings.Builder
+	j.mu.Lock()
+	for _, chunk := range j.chunks {
+		raw.WriteString(chunk.Data)
+	}
+	j.mu.Unlock()
+	lines := output.Lines(raw.String())
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// Follow returns an iterator over the job output: the retained output first,
+// then output as it arrives. Iteration ends when the job exits, yielding an
+// *ExitError if it exited with a non-zero status, or when ctx is done,
+// yielding ctx.Err().
+func (j *Job) Follow(ctx context.Context) iter.Seq2[OutputChunk, error] {
+	return func(yield func(OutputChunk, error) bool) {
+		if err := j.attach(ctx); err != nil {
+			yield(OutputChunk{}, err)
+			return
+		}
+		next := 0
+		for {
+			j.mu.Lock()
+			// Chunks dropped from the retained output are skipped.
+			next = max(next, j.base)
+			pending := slices.Clone(j.chunks[next-j.base:])
+			next += len(pending)
+			changed, finished := j.changed, j.finished
+			j.mu.Unlock()
+
+			for _, chunk := range pending {
+				if !yield(chunk, nil) {
+					return
+				}
+			}
+			if finished {
+				if err := j.result(); err != nil {
+					yield(OutputChunk{}, err)
+				}
+				return
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				yield(OutputChunk{}, ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+// Kill sends KILL to the job.
+func (j *Job) Kill(ctx context.Context) error {
+	return j.Signal(ctx, "KILL")
+}
+
+// Signal sends a signal such as "INT" or "TERM" to the job.
+func (j *Job) Signal(ctx context.Context, signal string) error {
+	_, err := j.sandbox.ContextSignal(ctx, j.ContextID, signal)
+	return err
+}
+
+// Stats returns the resource usage of the job.
+func (j *Job) Stats(ctx context.Context) (*apispec.ContextStatsResponse, error) {
+	return j.sandbox.ContextStats(ctx, j.ContextID)
+}
+
+// Delete deletes the job context, stopping the command if it is still
+// running, and stops tracking the job.
+func (j *Job) Delete(ctx context.Context) error {
+	if _, err := j.sandbox.DeleteContext(ctx, j.ContextID); err != nil && !isNotFound(err) {
+		return err
+	}
+	j.untrack()
+	return nil
+}
+
+// Close stops reading the job output and polling its context, and stops
+// tracking the job. The command keeps running; Jobs lists it again. Wait and
+// Follow then return ErrJobClosed unless the command exited before.
+func (j *Job) Close() error {
+	j.stop()
+	j.untrack()
+	j.mu.Lock()
+	attached := j.attached
+	j.mu.Unlock()
+	if !attached {
+		j.finish(-1, false, ErrJobClosed)
+	}
+	return nil
+}
+
+// attach starts reading the job output over the context WebSocket, unless it
+// is already being read. The server replays earlier output to the new connection.
+func (j *Job) attach(ctx context.Context) error {
+	j.mu.Lock()
+	attached := j.attached
+	j.mu.Unlock()
+	if attached {
+		return nil
+	}
+	if j.stopCtx.Err() != nil {
+		return ErrJobClosed
+	}
+	// The stream outlives ctx; Close ends it.
+	stream, err := j.sandbox.OpenStream(j.stopCtx, j.ContextID)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	if j.attached {
+		// A concurrent call attached first.
+		j.mu.Unlock()
+		_ = stream.Close()
+		return nil
+	}
+	j.attached = true
+	j.mu.Unlock()
+	go j.readLoop(stream)
+	return nil
+}
+
+func (j *Job) readLoop(stream *ContextStream) {
+	defer stream.Close()
+	out := &jobOutput{job: j}
+	j.mu.Lock()
+	if j.marker != "" {
+		out.useMarker(j.marker)
+	}
+	j.mu.Unlock()
+
+	var streamErr error
+	for msg, err := range stream.Messages() {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		switch msg := msg.(type) {
+		case StreamOutput:
+			out.write(msg.Source, msg.Data)
+		}
+	}
+	out.flush()
+	if streamErr != nil && j.stopCtx.Err() == nil {
+		// The WebSocket dropped; the command may still be running.
+		streamErr = j.pollUntilExited()
+	}
+	if j.stopCtx.Err() != nil {
+		streamErr = ErrJobClosed
+	}
+	j.finish(out.exitCode, out.haveStatus, streamErr)
+}
+
+// pollUntilExited waits for the job context to stop running, or for Close.
+func (j *Job) pollUntilExited() error {
+	ctx := j.stopCtx
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := j.sandbox.GetContext(ctx, j.ContextID)
+		if isNotFound(err) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ErrJobClosed
+		}
+		if err != nil {
+			return err
+		}
+		if !info.Running && !info.Paused {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ErrJobClosed
+		}
+	}
+}
+
+func (j *Job) finish(exitCode int, haveStatus bool, err error) {
+	j.mu.Lock()
+	if j.finished {
+		j.mu.Unlock()
+		return
+	}
+	j.finished = true
+	if haveStatus {
+		j.exitCode, j.haveStatus = exitCode, true
+	}
+	j.err = err
+	close(j.done)
+	j.notifyLocked()
+	autoDelete := j.autoDelete && !errors.Is(err, ErrJobClosed)
+	j.mu.Unlock()
+
+	if autoDelete {
+		j.sandbox.deleteContextQuietly(context.Background(), j.ContextID)
+		j.untrack()
+	}
+	// Release the stop context; Close has no effect on a finished job.
+	j.stop()
+}
+
+// result returns the outcome of a finished job.
+func (j *Job) result() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil {
+		return j.err
+	}
+	if j.haveStatus && j.exitCode != 0 {
+		var stderr strings.Builder
+		for _, chunk := range j.chunks {
+			if chunk.Source == OutputSourceStderr {
+				stderr.WriteString(chunk.Data)
+			}
+		}
+		return &ExitError{
+			SandboxID: j.SandboxID,
+			ContextID: j.ContextID,
+			Command:   strings.Join(j.Command, " "),
+			ExitCode:  j.exitCode,
+			Stderr:    stderr.String(),
+		}
+	}
+	return nil
+}
+
+func (j *Job) append(source string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.chunks = append(j.chunks, OutputChunk{Source: source, Data: string(data)})
+	j.size += len(data)
+	for j.size > jobLogLimit && len(j.chunks) > 1 {
+		j.size -= len(j.chunks[0].Data)
+		j.chunks[0] = OutputChunk{}
+		j.chunks = j.chunks[1:]
+		j.base++
+	}
+	j.notifyLocked()
+}
+
+// notifyLocked wakes Follow iterators waiting for output. j.mu must be held.
+func (j *Job) notifyLocked() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *Job) untrack() {
+	j.sandbox.mu.Lock()
+	if j.sandbox.jobs[j.ContextID] == j {
+		delete(j.sandbox.jobs, j.ContextID)
+	}
+	j.sandbox.mu.Unlock()
+}
+
+// jobOutput routes job output by source through exit status filters.
+// It is used by the job reader goroutine only.
+type jobOutput struct {
+	job        *Job
+	status     map[string]*exitStatusWriter
+	exitCode   int
+	haveStatus bool
+}
+
+// useMarker filters marker from the output. With a PTY both streams arrive as
+// stdout, so the marker is looked for in each.
+func (o *jobOutput) useMarker(marker string) {
+	o.status = map[string]*exitStatusWriter{
+		OutputSourceStdout: newExitStatusWriter(jobWriter{job: o.job, source: OutputSourceStdout}, marker),
+		OutputSourceStderr: newExitStatusWriter(jobWriter{job: o.job, source: OutputSourceStderr}, marker),
+	}
+}
+
+func (o *jobOutput) write(source, data string) {
+	if source != OutputSourceStderr {
+		source = OutputSourceStdout
+	}
+	if o.status == nil {
+		// Discovered jobs learn the marker from the output.
+		marker := exitMarkerRe.FindString(data)
+		if marker == "" {
+			o.job.append(source, []byte(data))
+			return
+		}
+		o.useMarker(marker)
+	}
+	_, _ = io.WriteString(o.status[source], data)
+}
+
+func (o *jobOutput) flush() {
+	for _, source := range []string{OutputSourceStderr, OutputSourceStdout} {
+		status := o.status[source]
+		if status == nil {
+			continue
+		}
+		_ = status.flush()
+		if code, ok := status.exitCode(); ok && !o.haveStatus {
+			o.exitCode, o.haveStatus = code, true
+		}
+	}
+}
+
+// jobWriter appends output of one source to a job.
+type jobWriter struct {
+	job    *Job
+	source string
+}
+
+func (w jobWriter) Write(p []byte) (int, error) {
+	w.job.append(w.source, p)
+	return len(p), nil
+}
@@ -0,0 +1,179 @@
+package sandbox0
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResultFileEnv is the environment variable that holds the path of the
+// result file for RunJSON and CmdJSON. Code that writes its JSON result to
+// this file is decoded reliably, whatever else it prints.
+const ResultFileEnv = "SANDBOX0_RESULT_FILE"
+
+// errNoJSONResult is wrapped in a *DecodeError when neither the result file
+// nor the output holds a JSON value.
+var errNoJSONResult = errors.New("no result file written and no JSON value in output")
+
+// resultFilePreludes set ResultFileEnv in the REPL of each language before
+// the code passed to RunJSON runs, without printing anything. The Node and
+// Ruby REPLs print the value of every input line, so their prelude shares the
+// first line of the code instead of ending with a newline.
+var resultFilePreludes = map[string]func(path string) string{
+	"python":     pythonResultPrelude,
+	"python3":    pythonResultPrelude,
+	"ipython":    pythonResultPrelude,
+	"node":       nodeResultPrelude,
+	"javascript": nodeResultPrelude,
+	"bash": func(path string) string {
+		return "export " + ResultFileEnv + "='" + path + "'\n"
+	},
+	"ruby": func(path string) string {
+		return "ENV['" + ResultFileEnv + "'] = '" + path + "'; "
+	},
+	"r": func(path string) string {
+		return "invisible(Sys.setenv(" + ResultFileEnv + " = '" + path + "'))\n"
+	},
+	"julia": func(path string) string {
+		return "ENV[\"" + ResultFileEnv + "\"] = \"" + path + "\";\n"
+	},
+}
+
+func pythonResultPrelude(path string) string {
+	return "__import__('os').environ['" + ResultFileEnv + "'] = '" + path + "'\n"
+}
+
+func nodeResultPrelude(path string) string {
+	return "process.env." + ResultFileEnv + " = '" + path + "'; "
+}
+
+// RunJSON runs code like Sandbox.Run and decodes its result into T.
+//
+// The code should write its result as JSON to the file named by the
+// ResultFileEnv environment variable, for example in Python:
+//
+//	json.dump(result, open(os.environ["SANDBOX0_RESULT_FILE"], "w"))
+//
+// If no result file is written, the output is decoded if it is a JSON value,
+// or else its last line that is. The environment variable is set for Python,
+// Node, Bash, Ruby, R and Julia; other languages can only print their result.
+// Errors from Run are returned as is; a result that cannot be decoded is
+// reported as a *DecodeError holding the raw output.
+func RunJSON[T any](ctx context.Context, s *Sandbox, language, code string, opts ...RunOption) (T, error) {
+	var value T
+	if s == nil {
+		return value, errors.New("sandbox cannot be nil")
+	}
+	path := newResultFilePath()
+	input := code
+	if prelude := resultFilePreludes[normalizeLanguage(language)]; prelude != nil && strings.TrimSpace(code) != "" {
+		input = prelude(path) + code
+	}
+	result, err := s.Run(ctx, language, input, opts...)
+	if err != nil {
+		s.removeResultFile(ctx, path)
+		return value, err
+	}
+	return decodeJSONResult[T](ctx, s, path, result.ContextID, result.Output, result.OutputRaw)
+}
+
+// CmdJSON runs cmd like Sandbox.Cmd and decodes its result into T.
+//
+// The command should write its result as JSON to the file named by the
+// ResultFileEnv environment variable, which is added to its environment. If
+// no result file is written, standard output is decoded if it is a JSON
+// value, or else its last line that is. Errors from Cmd, such as an
+// *ExitError, are returned as is; a result that cannot be decoded is
+// reported as a *DecodeError holding the raw output.
+func CmdJSON[T any](ctx context.Context, s *Sandbox, cmd string, opts ...CmdOption) (T, error) {
+	var value T
+	if s == nil {
+		return value, errors.New("sandbox cannot be nil")
+	}
+	path := newResultFilePath()
+	opts = append(slices.Clone(opts), withCmdEnvVar(ResultFileEnv, path))
+	result, err := s.Cmd(ctx, cmd, opts...)
+	if err != nil {
+		s.removeResultFile(ctx, path)
+		return value, err
+	}
+	return decodeJSONResult[T](ctx, s, path, result.ContextID, result.Stdout, result.OutputRaw)
+}
+
+// decodeJSONResult decodes the result file at path, or the last JSON value in
+// output when the file does not exist, and removes the file.
+func decodeJSONResult[T any](ctx context.Context, s *Sandbox, path, contextID, output, raw string) (T, error) {
+	var value T
+	data, err := s.ReadFile(ctx, path)
+	switch {
+	case err == nil:
+		s.removeResultFile(ctx, path)
+	case isNotFound(err):
+		data = lastJSONValue(output)
+	default:
+		s.removeResultFile(ctx, path)
+		return value, err
+	}
+	decodeErr := &DecodeError{SandboxID: s.ID, ContextID: contextID, Data: string(data), Output: raw}
+	if data == nil {
+		decodeErr.Err = errNoJSONResult
+		return value, decodeErr
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		decodeErr.Err = err
+		return value, decodeErr
+	}
+	return value, nil
+}
+
+// removeResultFile deletes the result file at path, if the code wrote one,
+// even when ctx is already done.
+func (s *Sandbox) removeResultFile(ctx context.Context, path string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_, _ = s.DeleteFile(ctx, path)
+}
+
+// lastJSONValue returns output if it is a single JSON value, or else its last
+// line that is one. It returns nil when there is none.
+func lastJSONValue(output string) []byte {
+	output = strings.TrimSpace(output)
+	if output != "" && json.Valid([]byte(output)) {
+		return []byte(output)
+	}
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && json.Valid([]byte(line)) {
+			return []byte(line)
+		}
+	}
+	return nil
+}
+
+// newResultFilePath returns a unique result file path. It only contains
+// [a-z0-9/._-], so it is safe inside quotes in every supported language.
+func newResultFilePath() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return "/tmp/sandbox0-result-" + hex.EncodeToString(buf[:]) + ".json"
+}
+
+// withCmdEnvVar adds one environment variable to those set with
+// WithCmdEnvVars.
+func withCmdEnvVar(name, value string) CmdOption {
+	return func(opts *cmdOptions) {
+		envVars := map[string]string{}
+		if opts.envVars != nil {
+			maps.Copy(envVars, *opts.envVars)
+		}
+		envVars[name] = value
+		opts.envVars = &envVars
+	}
+}
@@ -0,0 +1,121 @@
+package sandbox0
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// outputBuffer collects output in memory. With a positive limit it keeps
+// only the first and the last half of limit bytes, and String marks where
+// output was dropped.
+type outputBuffer struct {
+	limit int
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+func newOutputBuffer(limit int) *outputBuffer {
+	return &outputBuffer{limit: limit}
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return len(p), nil
+	}
+	rest := p
+	if room := b.headLimit() - len(b.head); room > 0 {
+		n := min(room, len(rest))
+		b.head = append(b.head, rest[:n]...)
+		rest = rest[n:]
+	}
+	tailLimit := b.limit / 2
+	if len(rest) == 0 || tailLimit == 0 {
+		return len(p), nil
+	}
+	b.tail = append(b.tail, rest...)
+	// Trim the tail only once it doubled, so writes stay cheap.
+	if len(b.tail) > 2*tailLimit {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-tailLimit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *outputBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+func (b *outputBuffer) headLimit() int {
+	return b.limit - b.limit/2
+}
+
+// Truncated reports whether output was dropped.
+func (b *outputBuffer) Truncated() bool {
+	return b.total > int64(len(b.head)+len(b.keptTail()))
+}
+
+func (b *outputBuffer) keptTail() []byte {
+	if b.limit <= 0 {
+		return b.tail
+	}
+	return b.tail[max(0, len(b.tail)-b.limit/2):]
+}
+
+// String returns the output. When output was dropped, a marker with the
+// number of dropped bytes separates the head and the tail, which are cut at
+// UTF-8 character boundaries.
+func (b *outputBuffer) String() string {
+	tail := b.keptTail()
+	if !b.Truncated() {
+		return string(b.head) + string(tail)
+	}
+	head := b.head
+	// Drop a character split by the cut at the end of the head.
+	for i := len(head) - 1; i >= max(0, len(head)-utf8.UTFMax); i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				head = head[:i]
+			}
+			break
+		}
+	}
+	// And one split at the start of the tail.
+	for i := 0; i < utf8.UTFMax && len(tail) > 0 && !utf8.RuneStart(tail[0]); i++ {
+		tail = tail[1:]
+	}
+	dropped := b.total - int64(len(head)+len(tail))
+	return string(head) + fmt.Sprintf("\n[... %d bytes truncated ...]\n", dropped) + string(tail)
+}
+
+// outputSink passes output on to a writer given with WithOutputSink or
+// WithCmdOutputSink. A failed write does not interrupt the execution: the
+// error is kept for the caller to return at the end, and later output is
+// discarded.
+type outputSink struct {
+	w   io.Writer
+	err error
+}
+
+func (s *outputSink) Write(p []byte) (int, error) {
+	if s.err == nil {
+		if _, err := s.w.Write(p); err != nil {
+			s.err = fmt.Errorf("sandbox0: write output sink: %w", err)
+		}
+	}
+	return len(p), nil
+}
+
+func (s *outputSink) WriteString(data string) (int, error) {
+	return s.Write([]byte(data))
+}
+
+// Err returns the first write error, if any. A nil sink has no error.
+func (s *outputSink) Err() error {
+	if s == nil {
+		return nil
+	}
+	return s.err
+}
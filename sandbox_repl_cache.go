@@ -0,0 +1,208 @@
+package sandbox0
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+// closeTimeout bounds the context deletions made by Sandbox.Close.
+const closeTimeout = 30 * time.Second
+
+// replCacheKey identifies a cached REPL context by language and the options
+// it was created with, so Run calls with different options get their own context.
+func replCacheKey(language string, options runOptions) string {
+	fingerprint := struct {
+		Cwd            *string             `json:"cwd,omitempty"`
+		EnvVars        *map[string]string  `json:"env_vars,omitempty"`
+		PTYSize        *apispec.PTYSize    `json:"pty_size,omitempty"`
+		IdleTimeoutSec *int32              `json:"idle_timeout_sec,omitempty"`
+		TTLSec         *int32              `json:"ttl_sec,omitempty"`
+		REPLConfig     *apispec.REPLConfig `json:"repl_config,omitempty"`
+	}{options.cwd, options.envVars, options.ptySize, options.idleTimeoutSec, options.ttlSec, options.replConfig}
+	data, err := json.Marshal(fingerprint)
+	if err != nil || string(data) == "{}" {
+		return language
+	}
+	sum := sha256.Sum256(data)
+	return language + "\x00" + hex.EncodeToString(sum[:8])
+}
+
+func replCacheKeyLanguage(key string) string {
+	language, _, _ := strings.Cut(key, "\x00")
+	return language
+}
+
+// healReplContext recovers from a failed exec on the cached context contextID.
+// A context that is gone is recreated; one that is no longer running is
+// restarted, or recreated if the restart fails. If the context is still
+// running, execErr is returned unchanged.
+func (s *Sandbox) healReplContext(ctx context.Context, contextID, language string, options runOptions, execErr error) (string, error) {
+	info, err := s.GetContext(ctx, contextID)
+	switch {
+	case err == nil && info.Running:
+		return "", execErr
+	case err == nil:
+		s.closeReplStream(contextID)
+		if _, err := s.RestartContext(ctx, contextID); err == nil {
+			return contextID, nil
+		}
+		s.deleteContextQuietly(ctx, contextID)
+	case !isNotFound(err):
+		return "", execErr
+	}
+
+	s.dropReplContext(contextID, language, options)
+	newID, _, err := s.ensureReplContext(ctx, language, options)
+	return newID, err
+}
+
+// dropReplContext removes contextID from the cache and closes its stream.
+func (s *Sandbox) dropReplContext(contextID, language string, options runOptions) {
+	key := replCacheKey(normalizeLanguage(language), options)
+	s.mu.Lock()
+	if s.replContexts[key] == contextID {
+		delete(s.replContexts, key)
+	}
+	s.mu.Unlock()
+	s.closeReplStream(contextID)
+}
+
+// replStream returns the context for language and options with the stream
+// RunStream reads it through.
+//
+// A stream is opened when the SDK creates a context and kept until the
+// context is dropped, so output is never replayed to RunStream: what arrives
+// between RunStream calls belongs to earlier inputs and is discarded. If the
+// stream of a cached context ended, the REPL exited or the context is gone,
+// and a new context is started instead.
+func (s *Sandbox) replStream(ctx context.Context, language string, options runOptions) (string, *ContextStream, error) {
+	contextID, cached, err := s.ensureReplContext(ctx, language, options)
+	if err != nil {
+		return "", nil, err
+	}
+	s.mu.Lock()
+	stream := s.replStreams[contextID]
+	s.mu.Unlock()
+	if stream != nil && !stream.ended() {
+		return contextID, stream, nil
+	}
+	if cached && stream != nil {
+		s.dropReplContext(contextID, language, options)
+		s.deleteContextQuietly(ctx, contextID)
+		if contextID, _, err = s.ensureReplContext(ctx, language, options); err != nil {
+			return "", nil, err
+		}
+	}
+	stream, err = s.openReplStream(ctx, contextID)
+	if err != nil {
+		return "", nil, err
+	}
+	return contextID, stream, nil
+}
+
+// openReplStream returns the open stream of contextID, connecting it if needed.
+// The stream outlives ctx and reconnects when the connection drops; it is
+// closed when the context is dropped from the cache or the Sandbox is closed.
+func (s *Sandbox) openReplStream(ctx context.Context, contextID string) (*ContextStream, error) {
+	s.mu.Lock()
+	existing := s.replStreams[contextID]
+	s.mu.Unlock()
+	if existing != nil && !existing.ended() {
+		return existing, nil
+	}
+	stream, err := s.OpenStream(context.WithoutCancel(ctx), contextID, WithStreamReconnect(0, 0))
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if existing := s.replStreams[contextID]; existing != nil && !existing.ended() {
+		// A concurrent call connected first.
+		s.mu.Unlock()
+		_ = stream.Close()
+		return existing, nil
+	}
+	s.replStreams[contextID] = stream
+	s.mu.Unlock()
+	return stream, nil
+}
+
+// closeReplStream closes the stream of contextID, if any.
+func (s *Sandbox) closeReplStream(contextID string) {
+	s.mu.Lock()
+	stream := s.replStreams[contextID]
+	delete(s.replStreams, contextID)
+	s.mu.Unlock()
+	if stream != nil {
+		_ = stream.Close()
+	}
+}
+
+// ResetREPL deletes the cached REPL contexts for language, so the next Run
+// starts a fresh interpreter. Contexts that no longer exist are ignored.
+func (s *Sandbox) ResetREPL(ctx context.Context, language string) error {
+	language = normalizeLanguage(language)
+	s.mu.Lock()
+	var contextIDs []string
+	for key, contextID := range s.replContexts {
+		if replCacheKeyLanguage(key) == language {
+			contextIDs = append(contextIDs, contextID)
+			delete(s.replContexts, key)
+		}
+	}
+	s.mu.Unlock()
+	for _, contextID := range contextIDs {
+		s.closeReplStream(contextID)
+	}
+	return s.deleteContexts(ctx, contextIDs)
+}
+
+// Close deletes every REPL context cached by Run and closes the streams
+// RunStream reads through. The sandbox itself is not deleted, and the
+// Sandbox remains usable afterwards.
+func (s *Sandbox) Close() error {
+	s.mu.Lock()
+	contextIDs := make([]string, 0, len(s.replContexts))
+	for key, contextID := range s.replContexts {
+		contextIDs = append(contextIDs, contextID)
+		delete(s.replContexts, key)
+	}
+	streams := s.replStreams
+	s.replStreams = map[string]*ContextStream{}
+	s.mu.Unlock()
+	for _, stream := range streams {
+		_ = stream.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return s.deleteContexts(ctx, contextIDs)
+}
+
+func (s *Sandbox) deleteContexts(ctx context.Context, contextIDs []string) error {
+	var errs []error
+	for _, contextID := range contextIDs {
+		if _, err := s.DeleteContext(ctx, contextID); err != nil && !isNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sandbox) deleteContextQuietly(ctx context.Context, contextID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_, _ = s.DeleteContext(ctx, contextID)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
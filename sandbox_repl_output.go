@@ -0,0 +1,247 @@
+package sandbox0
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/output"
+)
+
+// defaultREPLPrompts lists the primary and continuation prompts of built-in REPLs.
+var defaultREPLPrompts = map[string][]string{
+	"python":  {">>> ", "... "},
+	"python3": {">>> ", "... "},
+	"ipython": {"In [", "   ...: "},
+	"node":    {"> ", "... "},
+	"bash":    {"$ ", "> "},
+	"ruby":    {">> ", "?> "},
+	"r":       {"> ", "+ "},
+	"julia":   {"julia> "},
+}
+
+// replErrorParsers maps a language to its exception parser.
+var replErrorParsers = map[string]func([]string) *REPLError{
+	"python":     parsePythonError,
+	"python3":    parsePythonError,
+	"ipython":    parsePythonError,
+	"node":       parseNodeError,
+	"javascript": parseNodeError,
+}
+
+// cleanREPLOutput renders raw REPL output and removes echoed input and prompts.
+// Extra prompts come from the context REPLPromptConfig custom prompt.
+func cleanREPLOutput(raw, language, input string, prompts ...string) []string {
+	prompts = append(prompts, defaultREPLPrompts[strings.ToLower(language)]...)
+	inputLines := strings.Split(strings.TrimRight(input, "\n"), "\n")
+	next := 0
+	var lines []string
+	for _, line := range output.Lines(raw) {
+		trimmed, hadPrompt := stripPrompts(line, prompts)
+		// Only prompted lines count as echo, so output equal to the input is kept
+		// when the REPL does not echo.
+		if hadPrompt && next < len(inputLines) && strings.TrimRight(trimmed, " ") == strings.TrimRight(inputLines[next], " ") {
+			next++
+			continue
+		}
+		if hadPrompt && strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+	return lines
+}
+
+// stripPrompts removes any leading prompts from line. Python may print
+// several prompts in a row, such as ">>> >>> ".
+func stripPrompts(line string, prompts []string) (string, bool) {
+	stripped := false
+	for {
+		matched := false
+		for _, prompt := range prompts {
+			if prompt == "" {
+				continue
+			}
+			if trimmed, ok := cutPrompt(line, prompt); ok {
+				line = trimmed
+				stripped, matched = true, true
+				break
+			}
+		}
+		if !matched {
+			return line, stripped
+		}
+	}
+}
+
+// cutPrompt removes prompt from the start of line. IPython prompts carry a
+// counter, so a prompt ending in "[" also consumes "<n>]: ".
+func cutPrompt(line, prompt string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, prompt)
+	if !ok {
+		// A prompt's trailing space is trimmed when nothing follows it.
+		if strings.TrimRight(prompt, " ") == line && line != "" {
+			return "", true
+		}
+		return line, false
+	}
+	if strings.HasSuffix(prompt, "[") {
+		end := strings.Index(rest, "]: ")
+		if end < 0 {
+			return line, false
+		}
+		if _, err := strconv.Atoi(rest[:end]); err != nil {
+			return line, false
+		}
+		rest = rest[end+len("]: "):]
+	}
+	return rest, true
+}
+
+var (
+	pythonFrameRe     = regexp.MustCompile(`^\s*File "([^"]*)", line (\d+)(?:, in (.+))?$`)
+	pythonExceptionRe = regexp.MustCompile(`^((?:[A-Za-z_]\w*\.)*[A-Z]\w*)(?:: (.*))?$`)
+	pythonMarkerRe    = regexp.MustCompile(`^\s*[\^~\s]+$`)
+	nodeExceptionRe   = regexp.MustCompile(`^Uncaught ([A-Z][\w$]*(?:Error|Exception)|Error)(?: \[[\w_]+\])?(?:: (.*))?$`)
+	nodeFrameRe       = regexp.MustCompile(`^\s+at (?:(.+?) \()?(.+?):(\d+):(\d+)\)?$`)
+)
+
+// parsePythonError extracts the exception printed by the Python REPL. Only a
+// traceback that ends the output is an exception raised by the code; one
+// followed by more output was printed by it, such as by traceback.print_exc.
+func parsePythonError(lines []string) *REPLError {
+	lines = trimTrailingBlank(lines)
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Traceback (most recent call last):") {
+			start = i
+		}
+	}
+	if start < 0 {
+		if replErr := parseIPythonError(lines); replErr != nil {
+			return replErr
+		}
+		// Syntax errors are reported without a Traceback header.
+		for i, line := range lines {
+			if pythonFrameRe.MatchString(line) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return nil
+		}
+	}
+
+	replErr := &REPLError{}
+	end := len(lines)
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if i == start && strings.HasPrefix(line, "Traceback") {
+			continue
+		}
+		if match := pythonFrameRe.FindStringSubmatch(line); match != nil {
+			lineNo, _ := strconv.Atoi(match[2])
+			replErr.Traceback = append(replErr.Traceback, TraceFrame{File: match[1], Line: lineNo, Function: match[3]})
+			continue
+		}
+		if strings.HasPrefix(line, " ") {
+			frames := replErr.Traceback
+			if len(frames) > 0 && frames[len(frames)-1].Code == "" && !pythonMarkerRe.MatchString(line) {
+				frames[len(frames)-1].Code = strings.TrimSpace(line)
+			}
+			continue
+		}
+		match := pythonExceptionRe.FindStringSubmatch(line)
+		if match == nil {
+			return nil
+		}
+		replErr.Type = match[1]
+		replErr.Message = match[2]
+		end = i + 1
+		break
+	}
+	if replErr.Type == "" || len(replErr.Traceback) == 0 || end != len(lines) {
+		return nil
+	}
+	replErr.Raw = strings.Join(lines[start:end], "\n")
+	return replErr
+}
+
+// trimTrailingBlank returns lines without its trailing blank lines.
+func trimTrailingBlank(lines []string) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[:end]
+}
+
+// parseIPythonError extracts the exception from IPython's verbose traceback,
+// whose header ends in "Traceback (most recent call last)" and whose frames
+// use "Cell In[n], line m". The exception must be the last line of lines.
+// Only the type and message are parsed.
+func parseIPythonError(lines []string) *REPLError {
+	start := -1
+	for i, line := range lines {
+		if strings.HasSuffix(strings.TrimSpace(line), "Traceback (most recent call last)") {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	last := len(lines) - 1
+	if last <= start {
+		return nil
+	}
+	match := pythonExceptionRe.FindStringSubmatch(lines[last])
+	if match == nil {
+		return nil
+	}
+	return &REPLError{
+		Type:    match[1],
+		Message: match[2],
+		Raw:     strings.Join(lines[start:], "\n"),
+	}
+}
+
+// parseNodeError extracts the last uncaught error printed by Node.
+func parseNodeError(lines []string) *REPLError {
+	start := -1
+	var match []string
+	for i, line := range lines {
+		if m := nodeExceptionRe.FindStringSubmatch(line); m != nil {
+			start, match = i, m
+		}
+	}
+	if start < 0 {
+		for i, line := range lines {
+			if value, ok := strings.CutPrefix(line, "Uncaught "); ok {
+				start, match = i, []string{line, "Error", value}
+			}
+		}
+		if start < 0 {
+			return nil
+		}
+	}
+
+	replErr := &REPLError{Type: match[1], Message: match[2]}
+	end := start + 1
+	for ; end < len(lines); end++ {
+		frame := nodeFrameRe.FindStringSubmatch(lines[end])
+		if frame == nil {
+			break
+		}
+		lineNo, _ := strconv.Atoi(frame[3])
+		column, _ := strconv.Atoi(frame[4])
+		replErr.Traceback = append(replErr.Traceback, TraceFrame{
+			File:     frame[2],
+			Line:     lineNo,
+			Column:   column,
+			Function: frame[1],
+		})
+	}
+	replErr.Raw = strings.Join(lines[start:end], "\n")
+	return replErr
+}
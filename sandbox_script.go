@@ -0,0 +1,216 @@
+package sandbox0
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scriptLauncher makes the uploaded script executable and then runs the
+// command line that follows it. The script path is passed as $0.
+const scriptLauncher = `chmod +x "$0" && exec "$@"`
+
+// scriptInterpreters maps script file extensions to the interpreter that
+// runs them.
+var scriptInterpreters = map[string][]string{
+	".py":   {"python3"},
+	".sh":   {"sh"},
+	".bash": {"bash"},
+	".js":   {"node"},
+	".mjs":  {"node"},
+	".cjs":  {"node"},
+	".rb":   {"ruby"},
+	".pl":   {"perl"},
+	".php":  {"php"},
+	".lua":  {"lua"},
+	".r":    {"Rscript"},
+	".jl":   {"julia"},
+}
+
+// scriptExtensions maps RunScript languages to the extension of the uploaded
+// script, which selects its interpreter.
+var scriptExtensions = map[string]string{
+	"python":     ".py",
+	"python3":    ".py",
+	"ipython":    ".py",
+	"bash":       ".bash",
+	"sh":         ".sh",
+	"node":       ".js",
+	"javascript": ".js",
+	"ruby":       ".rb",
+	"perl":       ".pl",
+	"php":        ".php",
+	"lua":        ".lua",
+	"r":          ".r",
+	"julia":      ".jl",
+}
+
+type scriptOptions struct {
+	args        []string
+	interpreter []string
+	cwd         *string
+	envVars     *map[string]string
+	ttlSec      *int32
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+// ScriptOption configures RunFile and RunScript.
+type ScriptOption func(*scriptOptions)
+
+// WithScriptArgs sets the arguments passed to a script run by RunFile.
+func WithScriptArgs(args ...string) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.args = args
+	}
+}
+
+// WithScriptInterpreter runs the script with argv followed by the script
+// path, instead of the interpreter chosen from its shebang or extension.
+func WithScriptInterpreter(argv ...string) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.interpreter = argv
+	}
+}
+
+// WithScriptCWD sets the working directory of the script.
+func WithScriptCWD(cwd string) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.cwd = &cwd
+	}
+}
+
+// WithScriptEnvVars sets environment variables for the script.
+func WithScriptEnvVars(envVars map[string]string) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.envVars = &envVars
+	}
+}
+
+// WithScriptTTL sets the TTL of the context the script runs in.
+func WithScriptTTL(ttlSec int32) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.ttlSec = &ttlSec
+	}
+}
+
+// WithScriptOutput streams the standard output and error of the script to
+// stdout and stderr as it arrives. Either may be nil. The output is also
+// collected in the returned CmdResult.
+func WithScriptOutput(stdout, stderr io.Writer) ScriptOption {
+	return func(opts *scriptOptions) {
+		opts.stdout = stdout
+		opts.stderr = stderr
+	}
+}
+
+// RunFile uploads the local script at localPath to a temporary path in the
+// sandbox, runs it and removes it afterwards.
+//
+// A script starting with a shebang line is executed directly; otherwise the
+// interpreter is chosen from the file extension, such as python3 for .py or
+// node for .js. Use WithScriptInterpreter to choose it explicitly. As with
+// Cmd, a non-zero exit status returns the result together with an *ExitError.
+func (s *Sandbox) RunFile(ctx context.Context, localPath string, opts ...ScriptOption) (CmdResult, error) {
+	if strings.TrimSpace(localPath) == "" {
+		return CmdResult{}, errors.New("script path cannot be empty")
+	}
+	script, err := os.ReadFile(localPath)
+	if err != nil {
+		return CmdResult{}, err
+	}
+	options := applyScriptOptions(opts)
+	ext := strings.ToLower(filepath.Ext(localPath))
+	return s.runScript(ctx, filepath.Base(localPath), ext, script, options)
+}
+
+// RunScript uploads script to a temporary path in the sandbox, runs it in the
+// interpreter for language with args and removes it afterwards. Supported
+// languages are python, bash, sh, node, ruby, perl, php, lua, r and julia.
+// With an empty language the script must start with a shebang line.
+// WithScriptArgs is ignored in favor of args.
+func (s *Sandbox) RunScript(ctx context.Context, language string, script io.Reader, args []string, opts ...ScriptOption) (CmdResult, error) {
+	if script == nil {
+		return CmdResult{}, errors.New("script cannot be nil")
+	}
+	data, err := io.ReadAll(script)
+	if err != nil {
+		return CmdResult{}, err
+	}
+	options := applyScriptOptions(opts)
+	options.args = args
+	ext := ""
+	if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+		var ok bool
+		if ext, ok = scriptExtensions[language]; !ok && options.interpreter == nil {
+			return CmdResult{}, fmt.Errorf("unsupported script language %q", language)
+		}
+	}
+	return s.runScript(ctx, "script", ext, data, options)
+}
+
+func applyScriptOptions(opts []ScriptOption) scriptOptions {
+	options := scriptOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// runScript uploads script with extension ext, runs it and removes it. name
+// identifies the script in an *ExitError.
+func (s *Sandbox) runScript(ctx context.Context, name, ext string, script []byte, options scriptOptions) (CmdResult, error) {
+	interpreter := options.interpreter
+	if interpreter == nil && !bytes.HasPrefix(script, []byte("#!")) {
+		var ok bool
+		if interpreter, ok = scriptInterpreters[ext]; !ok {
+			return CmdResult{}, fmt.Errorf("cannot choose an interpreter for %s: add a shebang line or use WithScriptInterpreter", name)
+		}
+	}
+
+	path := newScriptPath(ext)
+	if _, err := s.WriteFile(ctx, path, script); err != nil {
+		return CmdResult{}, err
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+		defer cancel()
+		_, _ = s.DeleteFile(cleanupCtx, path)
+	}()
+
+	// The launcher already needs a POSIX shell, so the exit status can be
+	// captured too.
+	exitStatus := true
+	command := []string{exitStatusShell, "-c", scriptLauncher, path}
+	command = append(command, interpreter...)
+	command = append(command, path)
+	command = append(command, options.args...)
+	result, err := s.runCmd(ctx, cmdOptions{
+		command:    command,
+		cwd:        options.cwd,
+		envVars:    options.envVars,
+		ttlSec:     options.ttlSec,
+		exitStatus: &exitStatus,
+		stdout:     options.stdout,
+		stderr:     options.stderr,
+	})
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Command = strings.Join(append([]string{name}, options.args...), " ")
+	}
+	return result, err
+}
+
+// newScriptPath returns a unique path for an uploaded script.
+func newScriptPath(ext string) string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return "/tmp/sandbox0-script-" + hex.EncodeToString(buf[:]) + ext
+}
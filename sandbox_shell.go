@@ -0,0 +1,409 @@
+package sandbox0
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrShellClosed is returned by ShellSession methods after Close or once the
+// shell process has exited.
+var ErrShellClosed = errors.New("sandbox0: shell closed")
+
+// shellSetup turns off line editing, prompts and the echo and CRLF translation
+// of the PTY so command output reaches the client unchanged. Readline echoes
+// input itself, so line editing must be off for stty -echo to take effect.
+const shellSetup = "set +o emacs +o vi; stty -echo -onlcr 2>/dev/null; PS1=''; PS2=''; unset PROMPT_COMMAND; HISTFILE=/dev/null; __s0_rc=0; "
+
+// ShellResult is the result of a command run by ShellSession.Exec.
+type ShellResult struct {
+	Command string
+	// Output holds the combined standard output and error of the command.
+	// The shell runs on a PTY, so the two streams cannot be told apart.
+	Output   string
+	ExitCode int
+	// Cwd is the working directory after the command.
+	Cwd       string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// ShellSession is a bash session in its own REPL context. Unlike Cmd, state
+// such as the working directory, shell variables and exported environment
+// persists between commands, and unlike Run, each command reports its own
+// output and exit status. Commands run one at a time; all methods are safe
+// for concurrent use.
+type ShellSession struct {
+	SandboxID string
+	ContextID string
+
+	sandbox *Sandbox
+	stream  *ContextStream
+	execMu  sync.Mutex
+
+	mu     sync.Mutex
+	cwd    string
+	env    map[string]string
+	closed bool
+}
+
+// Shell starts a bash session in a new REPL context. It accepts the REPL
+// options of Run, such as WithCWD and WithEnvVars; WithContextID is ignored.
+// The context is not shared with Run and is deleted by Close.
+func (s *Sandbox) Shell(ctx context.Context, opts ...RunOption) (*ShellSession, error) {
+	language, options := s.runOptions("bash", opts)
+	contextResp, err := s.CreateContext(ctx, replContextRequest(language, options))
+	if err != nil {
+		return nil, err
+	}
+	if contextResp == nil {
+		return nil, errors.New("create context returned nil response")
+	}
+	shell := &ShellSession{
+		SandboxID: s.ID,
+		ContextID: contextResp.ID,
+		sandbox:   s,
+		env:       map[string]string{},
+	}
+	shell.stream, err = s.OpenStream(context.WithoutCancel(ctx), contextResp.ID)
+	if err == nil {
+		_, err = shell.exec(ctx, "", shellSetup)
+	}
+	if err != nil {
+		_ = shell.Close()
+		return nil, err
+	}
+	return shell, nil
+}
+
+// Exec runs cmd in the shell and waits for it to finish. The command is
+// evaluated by the shell itself, so cd, export and variable assignments
+// affect later commands. Standard input is /dev/null.
+//
+// A non-zero exit status returns the result together with an *ExitError.
+// A command that ends the shell, such as exit, returns ErrShellClosed.
+// If ctx is done first, the command is interrupted and Exec returns
+// ctx.Err(); the shell is closed if it does not recover within the client's
+// cancel grace period.
+func (sh *ShellSession) Exec(ctx context.Context, cmd string) (ShellResult, error) {
+	if strings.TrimSpace(cmd) == "" {
+		return ShellResult{}, errors.New("command cannot be empty")
+	}
+	token := newShellToken()
+	// The command is read from a quoted here-document so it is evaluated
+	// exactly as given, whatever quotes or newlines it contains.
+	script := "IFS= read -r -d '' __s0_cmd <<'__s0_in_" + token + "'\n" +
+		cmd + "\n__s0_in_" + token + "\n" +
+		"eval \"$__s0_cmd\" </dev/null; __s0_rc=$?; unset __s0_cmd; "
+	result, err := sh.exec(ctx, cmd, script)
+	if err != nil {
+		return result, err
+	}
+	if result.ExitCode != 0 {
+		return result, &ExitError{
+			SandboxID: sh.SandboxID,
+			ContextID: sh.ContextID,
+			Command:   cmd,
+			ExitCode:  result.ExitCode,
+		}
+	}
+	return result, nil
+}
+
+// Cwd returns the working directory after the last command.
+func (sh *ShellSession) Cwd() string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.cwd
+}
+
+// Env returns a copy of the exported environment after the last command.
+func (sh *ShellSession) Env() map[string]string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return maps.Clone(sh.env)
+}
+
+// Close ends the session and deletes its context.
+func (sh *ShellSession) Close() error {
+	sh.mu.Lock()
+	sh.closed = true
+	sh.mu.Unlock()
+	if sh.stream != nil {
+		_ = sh.stream.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if _, err := sh.sandbox.DeleteContext(ctx, sh.ContextID); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// exec sends script followed by a trailer that reports the exit status,
+// working directory and environment between markers, and waits for the
+// trailer to be printed.
+func (sh *ShellSession) exec(ctx context.Context, cmd, script string) (ShellResult, error) {
+	sh.execMu.Lock()
+	defer sh.execMu.Unlock()
+	sh.mu.Lock()
+	closed := sh.closed
+	sh.mu.Unlock()
+	if closed {
+		return ShellResult{}, ErrShellClosed
+	}
+
+	result := ShellResult{Command: cmd, StartedAt: time.Now()}
+	token := newShellToken()
+	if _, err := sh.stream.SendInput(script + shellTrailer(token)); err != nil {
+		return result, sh.fail(err)
+	}
+	raw, err := sh.await(ctx, token)
+	if err != nil && ctx.Err() != nil {
+		sh.resync()
+		return result, ctx.Err()
+	}
+	if err != nil {
+		return result, sh.fail(err)
+	}
+	result.Duration = time.Since(result.StartedAt)
+
+	output, exitCode, cwd, env, err := parseShellTrailer(raw, token)
+	if err != nil {
+		return result, sh.fail(err)
+	}
+	result.Output, result.ExitCode, result.Cwd = output, exitCode, cwd
+	sh.mu.Lock()
+	sh.cwd, sh.env = cwd, env
+	sh.mu.Unlock()
+	return result, nil
+}
+
+// await reads output until the trailer marked with token has been printed and
+// returns everything read, with PTY line endings normalized.
+func (sh *ShellSession) await(ctx context.Context, token string) (string, error) {
+	end := shellEndMarker(token)
+	var buf strings.Builder
+	for {
+		msg, err := sh.stream.next(ctx)
+		if err != nil {
+			return "", err
+		}
+		output, ok := msg.(StreamOutput)
+		if !ok {
+			continue
+		}
+		buf.WriteString(output.Data)
+		text := strings.ReplaceAll(buf.String(), "\r\n", "\n")
+		if strings.Contains(text, end) {
+			return text, nil
+		}
+	}
+}
+
+// resync interrupts the running command and waits for the shell to accept
+// input again, closing the shell if it does not.
+func (sh *ShellSession) resync() {
+	_ = sh.stream.Signal("INT")
+	token := newShellToken()
+	if _, err := sh.stream.SendInput("__s0_rc=130; " + shellTrailer(token)); err != nil {
+		_ = sh.fail(err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sh.sandbox.client.cancelGrace())
+	defer cancel()
+	raw, err := sh.await(ctx, token)
+	if err != nil {
+		_ = sh.fail(err)
+		return
+	}
+	if _, _, cwd, env, err := parseShellTrailer(raw, token); err == nil {
+		sh.mu.Lock()
+		sh.cwd, sh.env = cwd, env
+		sh.mu.Unlock()
+	}
+}
+
+// fail closes the shell after it stopped responding or its process exited.
+func (sh *ShellSession) fail(err error) error {
+	_ = sh.Close()
+	if errors.Is(err, ErrStreamClosed) {
+		return ErrShellClosed
+	}
+	return err
+}
+
+// shellTrailer prints the exit status in $__s0_rc, the working directory and
+// the exported environment between two markers. The markers are split in the
+// printf arguments so the input itself never contains them.
+func shellTrailer(token string) string {
+	return `printf '\n%s%s %d\n%s\n' __s0_ ` + token + ` "$__s0_rc" "$PWD"; export -p; printf '%s%s\n' __s0_end_ ` + token + "\n"
+}
+
+func shellStartMarker(token string) string {
+	return "__s0_" + token + " "
+}
+
+func shellEndMarker(token string) string {
+	return "__s0_end_" + token + "\n"
+}
+
+func newShellToken() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// parseShellTrailer splits the text read by ShellSession.await into the command
+// output and the exit status, working directory and environment reported by
+// the trailer.
+func parseShellTrailer(text, token string) (string, int, string, map[string]string, error) {
+	start := strings.LastIndex(text, shellStartMarker(token))
+	end := strings.LastIndex(text, shellEndMarker(token))
+	if start < 0 || end < start {
+		return "", 0, "", nil, errors.New("shell trailer not found in output")
+	}
+	// The trailer starts with a newline so its marker is always on its own line.
+	output := strings.TrimSuffix(text[:start], "\n")
+
+	status, rest, _ := strings.Cut(text[start+len(shellStartMarker(token)):end], "\n")
+	exitCode, err := strconv.Atoi(strings.TrimSpace(status))
+	if err != nil {
+		return "", 0, "", nil, fmt.Errorf("parse shell exit status %q: %w", status, err)
+	}
+	cwd, exports, _ := strings.Cut(rest, "\n")
+	return output, exitCode, cwd, parseExports(exports), nil
+}
+
+// parseExports parses the output of bash's export -p, which prints one
+// `declare -x NAME="value"` per variable. Values are double-quoted with \, ",
+// $ and ` escaped, or ANSI-C quoted as $'value' when they contain control
+// characters; variables exported without a value have no `=value` part.
+func parseExports(text string) map[string]string {
+	env := map[string]string{}
+	const prefix = "declare -x "
+	for len(text) > 0 {
+		if !strings.HasPrefix(text, prefix) {
+			// Skip anything that is not a declaration, such as output of a
+			// background job printed in between.
+			_, text, _ = strings.Cut(text, "\n")
+			continue
+		}
+		text = text[len(prefix):]
+		i := strings.IndexAny(text, "=\n")
+		if i < 0 || text[i] == '\n' {
+			var name string
+			name, text, _ = strings.Cut(text, "\n")
+			env[name] = ""
+			continue
+		}
+		name := text[:i]
+		text = text[i+1:]
+		var value string
+		switch {
+		case strings.HasPrefix(text, `"`):
+			value, text = unquoteExport(text[1:])
+		case strings.HasPrefix(text, "$'"):
+			value, text = unquoteANSIC(text[2:])
+		default:
+			value, text, _ = strings.Cut(text, "\n")
+		}
+		env[name] = value
+	}
+	return env
+}
+
+// unquoteExport decodes a double-quoted export -p value up to its closing
+// quote and returns it with the text after the end of its line.
+func unquoteExport(text string) (string, string) {
+	var value strings.Builder
+	i := 0
+	for ; i < len(text) && text[i] != '"'; i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\\\"$`", text[i+1]) >= 0 {
+			i++
+		}
+		value.WriteByte(text[i])
+	}
+	_, rest, _ := strings.Cut(text[min(i, len(text)):], "\n")
+	return value.String(), rest
+}
+
+// unquoteANSIC decodes a $'...' value up to its closing quote and returns it
+// with the text after the end of its line.
+func unquoteANSIC(text string) (string, string) {
+	var value strings.Builder
+	i := 0
+	for ; i < len(text) && text[i] != '\''; i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			value.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch c := text[i]; c {
+		case 'a':
+			value.WriteByte('\a')
+		case 'b':
+			value.WriteByte('\b')
+		case 'e', 'E':
+			value.WriteByte(0x1b)
+		case 'f':
+			value.WriteByte('\f')
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case 'v':
+			value.WriteByte('\v')
+		case 'x', 'u', 'U':
+			digits := 2
+			switch c {
+			case 'u':
+				digits = 4
+			case 'U':
+				digits = 8
+			}
+			j := i + 1
+			for j < len(text) && j-i-1 < digits && isHexDigit(text[j]) {
+				j++
+			}
+			n, err := strconv.ParseUint(text[i+1:j], 16, 32)
+			if err != nil {
+				value.WriteByte('\\')
+				value.WriteByte(c)
+				continue
+			}
+			if c == 'x' {
+				value.WriteByte(byte(n))
+			} else {
+				value.WriteRune(rune(n))
+			}
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(text) && j-i < 3 && text[j] >= '0' && text[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(text[i:j], 8, 16)
+			value.WriteByte(byte(n))
+			i = j - 1
+		default:
+			// \\, \', \" and \? stand for the character itself.
+			value.WriteByte(c)
+		}
+	}
+	_, rest, _ := strings.Cut(text[min(i, len(text)):], "\n")
+	return value.String(), rest
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
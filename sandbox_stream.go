@@ -0,0 +1,274 @@
+package sandbox0
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errStreamConsumed is yielded when a RunStream or CmdStream iterator is ranged over twice.
+var errStreamConsumed = errors.New("sandbox0: output stream already consumed")
+
+// errStreamStopped is returned to the command reader when the consumer stops iterating.
+var errStreamStopped = errors.New("sandbox0: output stream stopped")
+
+// OutputChunk is a piece of output yielded by RunStream and CmdStream.
+type OutputChunk struct {
+	// Source is OutputSourceStdout, OutputSourceStderr or OutputSourcePrompt.
+	Source string
+	Data   string
+}
+
+// RunStream is like Run but yields output as it arrives over the context WebSocket.
+//
+// The input is executed when the iterator is ranged over; the iterator can be
+// used only once. When iteration ends, the returned RunResult holds the
+// output, parsed like Run. A failed execution ends iteration by yielding a
+// non-nil error.
+//
+// Only output that arrives after the input is sent is yielded. RunStream keeps
+// a connection to each REPL context it uses for this, since the server
+// replays earlier output to new connections; for a context given with
+// WithContextID, replayed output may be yielded on the first call.
+//
+// Breaking out of the loop or cancelling ctx sends an INT signal to the REPL,
+// interrupting the running code while keeping the REPL context.
+func (s *Sandbox) RunStream(ctx context.Context, language, input string, opts ...RunOption) (iter.Seq2[OutputChunk, error], *RunResult) {
+	result := &RunResult{SandboxID: s.ID}
+	var used atomic.Bool
+	seq := func(yield func(OutputChunk, error) bool) {
+		if used.Swap(true) {
+			yield(OutputChunk{}, errStreamConsumed)
+			return
+		}
+		stopped := false
+		err := s.runStream(ctx, language, input, opts, result, func(chunk OutputChunk) bool {
+			stopped = !yield(chunk, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(OutputChunk{}, err)
+		}
+	}
+	return seq, result
+}
+
+func (s *Sandbox) runStream(ctx context.Context, language, input string, opts []RunOption, result *RunResult, yield func(OutputChunk) bool) error {
+	if strings.TrimSpace(input) == "" {
+		return errors.New("input cannot be empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	language, options := s.runOptions(language, opts)
+	contextID, stream, err := s.replStream(ctx, language, options)
+	if err != nil {
+		return err
+	}
+	// The stream is shared by the RunStream calls on the context.
+	stream.execMu.Lock()
+	defer stream.execMu.Unlock()
+	stop := context.AfterFunc(ctx, func() {
+		_ = stream.Signal("INT")
+	})
+	defer stop()
+
+	if !strings.HasSuffix(input, "\n") {
+		input += "\n"
+	}
+	// Output received since the last call belongs to earlier inputs.
+	stream.discardBacklog()
+	result.ContextID = contextID
+	result.StartedAt = time.Now()
+	requestID, err := stream.SendInput(input)
+	if err != nil {
+		return err
+	}
+
+	raw := newOutputBuffer(options.maxOutputBytes)
+	stdout := newOutputBuffer(options.maxOutputBytes)
+	stderr := newOutputBuffer(options.maxOutputBytes)
+	var sink *outputSink
+	if options.sink != nil {
+		sink = &outputSink{w: options.sink}
+	}
+	collect := func() {
+		result.OutputRaw = raw.String()
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		result.Truncated = raw.Truncated()
+		result.Duration = time.Since(result.StartedAt)
+	}
+	for {
+		msg, err := stream.next(ctx)
+		if err != nil {
+			collect()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if errors.Is(err, ErrStreamClosed) {
+				return errors.New("context stream closed before execution completed")
+			}
+			return err
+		}
+		switch msg := msg.(type) {
+		case StreamOutput:
+			_, _ = raw.WriteString(msg.Data)
+			if msg.Source == OutputSourceStderr {
+				_, _ = stderr.WriteString(msg.Data)
+			} else {
+				_, _ = stdout.WriteString(msg.Data)
+			}
+			if sink != nil {
+				_, _ = sink.WriteString(msg.Data)
+			}
+			if !yield(OutputChunk(msg)) {
+				_ = stream.Signal("INT")
+				collect()
+				return nil
+			}
+		case StreamDone:
+			if msg.RequestID != requestID {
+				continue
+			}
+			collect()
+			err := s.parseRunOutput(result, language, input, options)
+			if sinkErr := sink.Err(); sinkErr != nil {
+				err = errors.Join(err, sinkErr)
+			}
+			return err
+		case StreamInputUnconfirmed:
+			if msg.RequestID != requestID {
+				continue
+			}
+			collect()
+			return fmt.Errorf("%w: %w", ErrInputUnconfirmed, msg.Err)
+		}
+	}
+}
+
+// CmdStream is like Cmd but yields output as it arrives over the context
+// WebSocket. WithCmdWait is ignored; the command always runs to completion.
+//
+// The command starts when the iterator is ranged over; the iterator can be
+// used only once. When iteration ends, the returned CmdResult holds the
+// output and exit status. A command that exits with a non-zero status ends
+// iteration by yielding an *ExitError.
+//
+// Breaking out of the loop or cancelling ctx stops the remote process as
+// described for CommandContext.
+// The context is not automatically deleted; use DeleteContext to clean up when done.
+func (s *Sandbox) CmdStream(ctx context.Context, cmd string, opts ...CmdOption) (iter.Seq2[OutputChunk, error], *CmdResult) {
+	result := &CmdResult{SandboxID: s.ID, ExitCode: -1}
+	var used atomic.Bool
+	seq := func(yield func(OutputChunk, error) bool) {
+		if used.Swap(true) {
+			yield(OutputChunk{}, errStreamConsumed)
+			return
+		}
+		stopped := false
+		err := s.cmdStream(ctx, cmd, opts, result, func(chunk OutputChunk) bool {
+			stopped = !yield(chunk, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(OutputChunk{}, err)
+		}
+	}
+	return seq, result
+}
+
+func (s *Sandbox) cmdStream(ctx context.Context, cmd string, opts []CmdOption, result *CmdResult, yield func(OutputChunk) bool) error {
+	options, err := parseCmdOptions(cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	remote := s.remoteCmd(ctx, options)
+	chunks := make(chan OutputChunk)
+	stop := make(chan struct{})
+	remote.Stdout = &chunkWriter{source: OutputSourceStdout, chunks: chunks, stop: stop}
+	remote.Stderr = &chunkWriter{source: OutputSourceStderr, chunks: chunks, stop: stop}
+	if err := remote.Start(); err != nil {
+		return err
+	}
+	result.ContextID = remote.ContextID
+	result.StartedAt = remote.StartedAt
+
+	waitErr := make(chan error, 1)
+	go func() {
+		// Wait returns after the reader has stopped writing chunks.
+		waitErr <- remote.Wait()
+		close(chunks)
+	}()
+
+	raw := newOutputBuffer(options.maxOutputBytes)
+	stdout := newOutputBuffer(options.maxOutputBytes)
+	stderr := newOutputBuffer(options.maxOutputBytes)
+	var sink *outputSink
+	if options.sink != nil {
+		sink = &outputSink{w: options.sink}
+	}
+	stopped := false
+	for chunk := range chunks {
+		if stopped {
+			continue
+		}
+		_, _ = raw.WriteString(chunk.Data)
+		if chunk.Source == OutputSourceStderr {
+			_, _ = stderr.WriteString(chunk.Data)
+		} else {
+			_, _ = stdout.WriteString(chunk.Data)
+		}
+		if sink != nil {
+			_, _ = sink.WriteString(chunk.Data)
+		}
+		if !yield(chunk) {
+			stopped = true
+			close(stop)
+			cancel()
+		}
+	}
+	err = <-waitErr
+
+	result.OutputRaw = raw.String()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = raw.Truncated()
+	result.ExitCode = remote.ExitCode()
+	result.Duration = time.Since(result.StartedAt)
+	if stopped {
+		return nil
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = result.Stderr
+	}
+	if sinkErr := sink.Err(); sinkErr != nil {
+		err = errors.Join(err, sinkErr)
+	}
+	return err
+}
+
+// chunkWriter hands output written by a RemoteCmd to the CmdStream iterator.
+type chunkWriter struct {
+	source string
+	chunks chan<- OutputChunk
+	stop   <-chan struct{}
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	select {
+	case w.chunks <- OutputChunk{Source: w.source, Data: string(p)}:
+		return len(p), nil
+	case <-w.stop:
+		return 0, errStreamStopped
+	}
+}
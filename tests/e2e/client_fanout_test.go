@@ -0,0 +1,172 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestFanOut(t *testing.T) {
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL("http://127.0.0.1:1"), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	var sandboxes []*sandbox0.Sandbox
+	for i := range 6 {
+		sandboxes = append(sandboxes, client.Sandbox(fmt.Sprintf("sb-%d", i)))
+	}
+
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var events []sandbox0.FanOutEvent
+	results, err := sandbox0.FanOut(context.Background(), client, sandboxes,
+		func(ctx context.Context, sandbox *sandbox0.Sandbox) (string, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			mu.Lock()
+			attempts[sandbox.ID]++
+			attempt := attempts[sandbox.ID]
+			mu.Unlock()
+
+			switch sandbox.ID {
+			case "sb-1":
+				// Fails once, then succeeds on retry.
+				if attempt == 1 {
+					return "", errors.New("flaky")
+				}
+			case "sb-3":
+				return "", errors.New("broken")
+			case "sb-4":
+				// Exceeds the task timeout.
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			time.Sleep(20 * time.Millisecond)
+			return "done-" + sandbox.ID, nil
+		},
+		sandbox0.WithFanOutConcurrency(2),
+		sandbox0.WithFanOutRetries(1, time.Millisecond),
+		sandbox0.WithFanOutTaskTimeout(50*time.Millisecond),
+		sandbox0.WithFanOutProgress(func(event sandbox0.FanOutEvent) {
+			events = append(events, event)
+		}),
+	)
+
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most 2 concurrent tasks, got %d", peak.Load())
+	}
+	if len(results) != len(sandboxes) {
+		t.Fatalf("expected %d results, got %d", len(sandboxes), len(results))
+	}
+	for i, result := range results {
+		if result.Index != i || result.SandboxID != sandboxes[i].ID {
+			t.Fatalf("result %d out of order: %+v", i, result)
+		}
+	}
+	if results[0].Value != "done-sb-0" || results[0].Attempts != 1 {
+		t.Fatalf("unexpected result 0: %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Attempts != 2 {
+		t.Fatalf("expected retried success, got %+v", results[1])
+	}
+	if results[3].Err == nil || results[3].Attempts != 2 {
+		t.Fatalf("expected failure after retry, got %+v", results[3])
+	}
+	if !errors.Is(results[4].Err, context.DeadlineExceeded) {
+		t.Fatalf("expected task timeout, got %v", results[4].Err)
+	}
+
+	var fanOutErr *sandbox0.FanOutError
+	if !errors.As(err, &fanOutErr) || fanOutErr.SandboxID != "sb-3" {
+		t.Fatalf("expected joined fan-out errors, got %v", err)
+	}
+	finished := 0
+	for _, event := range events {
+		if event.Total != len(sandboxes) {
+			t.Fatalf("unexpected total in event: %+v", event)
+		}
+		if event.Type == sandbox0.FanOutFinished {
+			finished++
+			if event.Completed != finished {
+				t.Fatalf("unexpected completed count in event: %+v", event)
+			}
+		}
+	}
+	if finished != len(sandboxes) {
+		t.Fatalf("expected %d finished events, got %d", len(sandboxes), finished)
+	}
+}
+
+func TestFanOutClaimsAndCleansUp(t *testing.T) {
+	var mu sync.Mutex
+	var claimed, deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		id := fmt.Sprintf("claimed-%d", len(claimed))
+		claimed = append(claimed, id)
+		mu.Unlock()
+		writeFakeSuccess(w, http.StatusCreated, map[string]any{
+			"sandbox_id": id,
+			"template":   "default",
+			"status":     "running",
+			"pod_name":   "pod-" + id,
+		})
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deleted = append(deleted, r.PathValue("id"))
+		mu.Unlock()
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"message": "deleted"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	results, err := sandbox0.FanOut(context.Background(), client, nil,
+		func(ctx context.Context, sandbox *sandbox0.Sandbox) (string, error) {
+			return sandbox.ID, nil
+		},
+		sandbox0.WithFanOutClaim("default", 3),
+	)
+	if err != nil {
+		t.Fatalf("fan out failed: %v", err)
+	}
+	var ids []string
+	for _, result := range results {
+		if result.Value != result.SandboxID {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		ids = append(ids, result.SandboxID)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(ids)
+	slices.Sort(claimed)
+	slices.Sort(deleted)
+	if !slices.Equal(ids, claimed) || !slices.Equal(deleted, claimed) {
+		t.Fatalf("claimed %v, deleted %v, results %v", claimed, deleted, ids)
+	}
+}
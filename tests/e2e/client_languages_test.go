@@ -0,0 +1,125 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func TestREPLPresets(t *testing.T) {
+	api, client := newFakeContextAPI(t, func(string) string { return "" })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Presets apply by default to languages the server does not provide.
+	presets := map[string]string{
+		"python":  "python3",
+		"ipython": "ipython",
+		"node":    "node",
+		"bash":    "bash",
+		"ruby":    "irb",
+		"R":       "R",
+		"julia":   "julia",
+	}
+	for language, executable := range presets {
+		t.Run(language, func(t *testing.T) {
+			preset, ok := sandbox0.REPLPreset(language)
+			if !ok || len(preset.Candidates) == 0 || !preset.Ready.IsSet() {
+				t.Fatalf("missing preset for %s: %+v", language, preset)
+			}
+			if _, err := client.Sandbox("sb-"+language).Run(ctx, language, "1\n"); err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			repl := createdREPL(t, api)
+			if repl["language"] != language {
+				t.Fatalf("expected language %s, got %v", language, repl["language"])
+			}
+			server := language == "python" || language == "node" || language == "bash"
+			if _, ok := repl["repl_config"]; ok == server {
+				t.Fatalf("unexpected repl_config for %s: %v", language, repl["repl_config"])
+			}
+			if server {
+				if _, err := client.Sandbox("sb-preset-"+language).Run(ctx, language, "1\n", sandbox0.WithREPLConfig(preset)); err != nil {
+					t.Fatalf("run with preset failed: %v", err)
+				}
+				repl = createdREPL(t, api)
+			}
+			if got := replCandidate(t, repl); got != executable {
+				t.Fatalf("expected candidate %s, got %s", executable, got)
+			}
+		})
+	}
+
+	if _, err := client.Sandbox("sb-unknown").Run(ctx, "cobol", "1\n"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if _, ok := createdREPL(t, api)["repl_config"]; ok {
+		t.Fatalf("unexpected repl_config for unknown language")
+	}
+}
+
+func TestRegisterLanguage(t *testing.T) {
+	api, client := newFakeContextAPI(t, func(string) string { return "" })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.RegisterLanguage("Deno", apispec.REPLConfig{}); err == nil {
+		t.Fatalf("expected error for config without candidates")
+	}
+	if err := client.RegisterLanguage("Deno", apispec.REPLConfig{
+		Candidates: []apispec.ExecCandidate{{Name: "deno", Args: []string{"repl"}}},
+		Prompt:     apispec.NewOptREPLPromptConfig(apispec.REPLPromptConfig{CustomPrompt: apispec.NewOptString("deno> ")}),
+	}); err != nil {
+		t.Fatalf("register language failed: %v", err)
+	}
+	if _, err := client.Sandbox("sb-deno").Run(ctx, "deno", "1\n"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	repl := createdREPL(t, api)
+	if got := replCandidate(t, repl); got != "deno" {
+		t.Fatalf("expected registered candidate, got %s", got)
+	}
+	if config := repl["repl_config"].(map[string]any); config["name"] != "deno" {
+		t.Fatalf("expected default config name, got %v", config["name"])
+	}
+
+	override := apispec.REPLConfig{
+		Name:       "python",
+		Candidates: []apispec.ExecCandidate{{Name: "/opt/venv/bin/python", Args: []string{"-i"}}},
+	}
+	if _, err := client.Sandbox("sb-override").Run(ctx, "python", "1\n", sandbox0.WithREPLConfig(override)); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := replCandidate(t, createdREPL(t, api)); got != "/opt/venv/bin/python" {
+		t.Fatalf("expected WithREPLConfig candidate, got %s", got)
+	}
+}
+
+func createdREPL(t *testing.T, api *fakeContextAPI) map[string]any {
+	t.Helper()
+	created := api.lastCreated()
+	repl, ok := created["repl"].(map[string]any)
+	if !ok {
+		t.Fatalf("create request has no repl section: %v", created)
+	}
+	return repl
+}
+
+func replCandidate(t *testing.T, repl map[string]any) string {
+	t.Helper()
+	config, ok := repl["repl_config"].(map[string]any)
+	if !ok {
+		t.Fatalf("create request has no repl_config: %v", repl)
+	}
+	candidates, _ := config["candidates"].([]any)
+	if len(candidates) == 0 {
+		t.Fatalf("repl_config has no candidates: %v", config)
+	}
+	name, _ := candidates[0].(map[string]any)["name"].(string)
+	return name
+}
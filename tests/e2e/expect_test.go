@@ -0,0 +1,72 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/expect"
+)
+
+func TestExpectSession(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	script := `printf '\033[1;33mContinue? [y/n]\033[0m '; read answer; echo "answer=$answer"; ` +
+		`for i in 1 2; do printf 'Retry? '; read r; done; printf 'Name: '; read name; echo "hello $name"`
+	session, err := expect.Spawn(ctx, sandbox, sandbox0.Shell(script))
+	if err != nil {
+		t.Fatalf("spawn failed: %v", err)
+	}
+	defer session.Close()
+
+	if _, err := session.Expect(`Continue\? \[y/n\] `, 10*time.Second); err != nil {
+		t.Fatalf("expect prompt failed: %v", err)
+	}
+	if err := session.SendLine("y"); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	match, err := session.Expect(`answer=(\w+)`, 10*time.Second)
+	if err != nil || match.Groups[1] != "y" {
+		t.Fatalf("unexpected answer match: %+v, %v", match, err)
+	}
+
+	retries := 0
+	index, err := session.ExpectAny(10*time.Second,
+		expect.Case{Pattern: `Retry\? `, Handle: func(s *expect.Session, _ expect.Match) error {
+			retries++
+			if err := s.SendLine("again"); err != nil {
+				return err
+			}
+			return expect.Continue
+		}},
+		expect.Case{Pattern: `Name: `, Handle: func(s *expect.Session, _ expect.Match) error {
+			return s.SendLine("sandbox")
+		}},
+	)
+	if err != nil || index != 1 || retries != 2 {
+		t.Fatalf("unexpected expect any result: index %d, retries %d, err %v", index, retries, err)
+	}
+	if _, err := session.Expect(`hello sandbox`, 10*time.Second); err != nil {
+		t.Fatalf("expect greeting failed: %v", err)
+	}
+	if _, err := session.Expect(`never printed`, 200*time.Millisecond); !errors.Is(err, expect.ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if _, err := session.ExpectEOF(10 * time.Second); err != nil {
+		t.Fatalf("expect eof failed: %v", err)
+	}
+	if transcript := session.Transcript(); strings.Contains(transcript, "\x1b") || !strings.Contains(transcript, "Continue? [y/n]") {
+		t.Fatalf("unexpected transcript: %q", transcript)
+	}
+}
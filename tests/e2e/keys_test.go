@@ -0,0 +1,79 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/expect"
+	"github.com/sandbox0-ai/sdk-go/pkg/keys"
+)
+
+func TestKeysEncode(t *testing.T) {
+	cases := []struct {
+		name  string
+		input keys.Input
+		want  string
+	}{
+		{"enter", keys.Enter, "\r"},
+		{"up", keys.Up, "\x1b[A"},
+		{"f1", keys.F1, "\x1bOP"},
+		{"f5", keys.F5, "\x1b[15~"},
+		{"ctrl left", keys.With(keys.ModCtrl, keys.Left), "\x1b[1;5D"},
+		{"ctrl shift right", keys.With(keys.ModCtrl|keys.ModShift, keys.Right), "\x1b[1;6C"},
+		{"alt delete", keys.With(keys.ModAlt, keys.Delete), "\x1b[3;3~"},
+		{"shift f2", keys.With(keys.ModShift, keys.F2), "\x1b[1;2Q"},
+		{"shift tab", keys.With(keys.ModShift, keys.Tab), "\x1b[Z"},
+		{"alt enter", keys.With(keys.ModAlt, keys.Enter), "\x1b\r"},
+		{"ctrl d", keys.Ctrl('d'), "\x04"},
+		{"ctrl C", keys.Ctrl('C'), "\x03"},
+		{"ctrl bracket", keys.Ctrl('['), "\x1b"},
+		{"ctrl space", keys.Ctrl(' '), "\x00"},
+		{"alt b", keys.Alt('b'), "\x1bb"},
+		{"shift a", keys.Combo{Mod: keys.ModShift, Rune: 'a'}, "A"},
+		{"text", keys.Text(":wq\n"), ":wq\n"},
+		{"paste", keys.Paste("a\x1b[20\x1b[201~1~b"), "\x1b[200~ab\x1b[201~"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.input.Encode(); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if got := keys.Encode(keys.Escape, keys.Text(":q"), keys.Enter); got != "\x1b:q\r" {
+		t.Fatalf("unexpected sequence: %q", got)
+	}
+}
+
+func TestSandboxSendKeys(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Read raw keys from the PTY and print them as hex bytes.
+	session, err := expect.Spawn(ctx, sandbox, sandbox0.Shell(`stty raw -echo; echo ready; head -c 7 | od -An -tx1`))
+	if err != nil {
+		t.Fatalf("spawn failed: %v", err)
+	}
+	defer session.Close()
+	if _, err := session.Expect(`ready`, 10*time.Second); err != nil {
+		t.Fatalf("expect ready failed: %v", err)
+	}
+	if _, err := sandbox.SendKeys(ctx, session.ContextID, keys.Up, keys.Ctrl('d')); err != nil {
+		t.Fatalf("send keys failed: %v", err)
+	}
+	if err := session.SendKeys(keys.With(keys.ModCtrl, keys.Left)); err != nil {
+		t.Fatalf("send keys over stream failed: %v", err)
+	}
+	if _, err := session.Expect(`1b 5b 41 04 1b 5b 31`, 10*time.Second); err != nil {
+		t.Fatalf("unexpected key bytes: %v\n%s", err, session.Transcript())
+	}
+}
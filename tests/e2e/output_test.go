@@ -0,0 +1,74 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"reflect"
+	"testing"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/output"
+)
+
+func TestOutputRender(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"crlf", "hello\r\nworld\r\n", "hello\nworld\n"},
+		{"colors", "\x1b[1;31merror\x1b[0m: boom\r\n", "error: boom\n"},
+		{"progress", "10%\r50%\r100%\r\ndone\r\n", "100%\ndone\n"},
+		{"erase line", "downloading...\r\x1b[Kfinished\r\n", "finished\n"},
+		{"cursor up", "a: 1\r\nb: 1\r\n\x1b[2A\x1b[2Ka: 2\r\n\x1b[2Kb: 2\r\n", "a: 2\nb: 2\n"},
+		{"backspace", "abc\b\bX\r\n", "aXc\n"},
+		{"osc title", "\x1b]0;title\x07prompt$ \r\n", "prompt$\n"},
+		{"utf8", "héllo ✓\r\n", "héllo ✓\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := output.Render(tc.raw); got != tc.want {
+				t.Fatalf("render %q: got %q, want %q", tc.raw, got, tc.want)
+			}
+		})
+	}
+
+	if got := (sandbox0.CmdResult{OutputRaw: "\x1b[32mok\x1b[0m\r\n"}).Text(); got != "ok\n" {
+		t.Fatalf("unexpected cmd text: %q", got)
+	}
+}
+
+func TestOutputScreen(t *testing.T) {
+	screen := output.NewScreen(10, 3)
+	// Write in small chunks to exercise sequences split across writes.
+	raw := "line1\r\nline2\r\nline3\r\nline4 is long\x1b[1;31m\r\n"
+	for i := 0; i < len(raw); i += 3 {
+		_, _ = screen.Write([]byte(raw[i:min(i+3, len(raw))]))
+	}
+	if got, want := screen.Scrollback(), []string{"line1", "line2", "line3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("scrollback: got %q, want %q", got, want)
+	}
+	if got, want := screen.Display(), []string{"line4 is l", "ong", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("display: got %q, want %q", got, want)
+	}
+	if row, col := screen.Cursor(); row != 2 || col != 0 {
+		t.Fatalf("cursor: got %d,%d", row, col)
+	}
+}
+
+func TestOutputStrip(t *testing.T) {
+	raw := "\x1b[?25l10%\r50%\r\n\x1b[1mbold\x1b[0m\tdone\x07\r\n"
+	if got, want := output.Strip(raw), "10%\n50%\nbold\tdone\n"; got != want {
+		t.Fatalf("strip: got %q, want %q", got, want)
+	}
+
+	// Split inside escape sequences, a CRLF and a UTF-8 character.
+	var stripper output.Stripper
+	var got string
+	for _, chunk := range []string{"\x1b[?2", "5l10%\r", "50%\r", "\n\x1b[1mbo", "ld\x1b[0m\tdone\x07\r\n\xc3", "\xa9\r\n"} {
+		got += stripper.Strip(chunk)
+	}
+	if want := "10%\n50%\nbold\tdone\n\u00e9\n"; got != want {
+		t.Fatalf("incremental strip: got %q, want %q", got, want)
+	}
+}
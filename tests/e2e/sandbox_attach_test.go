@@ -0,0 +1,74 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func TestSandboxAttach(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ctxResp, err := sandbox.CreateContext(ctx, apispec.CreateContextRequest{
+		Type: apispec.NewOptProcessType(apispec.ProcessTypeRepl),
+		Repl: apispec.NewOptCreateREPLContextRequest(apispec.CreateREPLContextRequest{
+			Language: apispec.NewOptString("bash"),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("create repl context failed: %v", err)
+	}
+	defer sandbox.DeleteContext(ctx, ctxResp.ID)
+
+	stdin, input := io.Pipe()
+	var output lockedBuffer
+	go func() {
+		_, _ = io.WriteString(input, "echo attached-$((20+22))\n")
+		deadline := time.Now().Add(20 * time.Second)
+		for time.Now().Before(deadline) && !strings.Contains(output.String(), "attached-42") {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = input.Write(sandbox0.DefaultDetachKeys)
+	}()
+
+	err = sandbox.Attach(ctx, ctxResp.ID, sandbox0.AttachOptions{Stdin: stdin, Stdout: &output, Stderr: &output})
+	if !errors.Is(err, sandbox0.ErrDetached) {
+		t.Fatalf("expected detach, got %v", err)
+	}
+	if !strings.Contains(output.String(), "attached-42") {
+		t.Fatalf("unexpected attach output: %q", output.String())
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,139 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxCancelStopsRemoteProcess(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	defer sandbox.Close()
+
+	cmdCtx, cmdCancel := context.WithTimeout(ctx, 2*time.Second)
+	result, err := sandbox.Cmd(cmdCtx, "sleep 60", sandbox0.WithCmdExitStatus(true))
+	cmdCancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if result.ContextID != "" {
+		info, err := sandbox.GetContext(ctx, result.ContextID)
+		if err == nil && info.Running {
+			t.Fatalf("command context %s still running after cancellation", result.ContextID)
+		}
+		_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+	}
+
+	runCtx, runCancel := context.WithTimeout(ctx, 2*time.Second)
+	_, err = sandbox.Run(runCtx, "python", "import time\ntime.sleep(60)\n")
+	runCancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// The interrupted REPL, or its replacement, accepts new input.
+	after, err := sandbox.Run(ctx, "python", "print('after-cancel')")
+	if err != nil {
+		t.Fatalf("run after cancel failed: %v", err)
+	}
+	if !strings.Contains(after.Output, "after-cancel") {
+		t.Fatalf("unexpected output after cancel: %q", after.Output)
+	}
+}
+
+func TestContextExecCancelEscalatesSignals(t *testing.T) {
+	var mu sync.Mutex
+	var signals []string
+	stopped := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts/{ctx}/exec", func(w http.ResponseWriter, r *http.Request) {
+		// The process ignores INT and stops on TERM.
+		<-stopped
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"output_raw": "Terminated\r\n"})
+	})
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts/{ctx}/signal", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Signal string `json:"signal"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		signals = append(signals, req.Signal)
+		mu.Unlock()
+		switch req.Signal {
+		case "INT":
+			writeFakeError(w, http.StatusInternalServerError, "signal failed")
+			return
+		case "TERM":
+			close(stopped)
+		}
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"signaled": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var cancelErrs []string
+	client, err := sandbox0.NewClient(
+		sandbox0.WithBaseURL(server.URL),
+		sandbox0.WithToken("test-token"),
+		sandbox0.WithCancelGracePeriod(100*time.Millisecond),
+		sandbox0.WithCancelErrorHandler(func(contextID string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			cancelErrs = append(cancelErrs, contextID+": "+err.Error())
+		}),
+	)
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.Sandbox("sb-1").ContextExec(ctx, "ctx-1", "while True: pass\n")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected ContextExec to return on cancel, took %s", elapsed)
+	}
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := client.WaitCancellations(waitCtx); err != nil {
+		t.Fatalf("wait for cancellations failed: %v", err)
+	}
+	// KILL is not sent once the call returns after TERM.
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(signals, []string{"INT", "TERM"}) {
+		t.Fatalf("unexpected signals: %v", signals)
+	}
+	if len(cancelErrs) != 1 || !strings.HasPrefix(cancelErrs[0], "ctx-1: send INT: ") {
+		t.Fatalf("expected the INT failure to be reported, got %q", cancelErrs)
+	}
+}
+
+func TestCancelGracePeriodOption(t *testing.T) {
+	if _, err := sandbox0.NewClient(sandbox0.WithCancelGracePeriod(0)); err == nil {
+		t.Fatalf("expected error for zero grace period")
+	}
+	if _, err := sandbox0.NewClient(sandbox0.WithCancelGracePeriod(time.Second)); err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func TestSandboxCommand(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cmd := sandbox.CommandContext(ctx, "sh", "-c", "echo $GREETING from $(pwd)")
+	cmd.Env = []string{"GREETING=hello"}
+	cmd.Dir = "/tmp"
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("command with env failed: %v", err)
+	}
+	if !strings.Contains(string(out), "hello from /tmp") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	cat := sandbox.CommandContext(ctx, "cat")
+	// Ctrl-D ends the input only on a terminal.
+	cat.PTYSize = &apispec.PTYSize{Rows: apispec.NewOptInt32(24), Cols: apispec.NewOptInt32(80)}
+	stdin, err := cat.StdinPipe()
+	if err != nil {
+		t.Fatalf("stdin pipe failed: %v", err)
+	}
+	stdout, err := cat.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe failed: %v", err)
+	}
+	if err := cat.Start(); err != nil {
+		t.Fatalf("start cat failed: %v", err)
+	}
+	if _, err := io.WriteString(stdin, "piped-input\n"); err != nil {
+		t.Fatalf("write stdin failed: %v", err)
+	}
+	_ = stdin.Close()
+	var got bytes.Buffer
+	if _, err := io.Copy(&got, stdout); err != nil {
+		t.Fatalf("read stdout failed: %v", err)
+	}
+	if err := cat.Wait(); err != nil {
+		t.Fatalf("wait cat failed: %v", err)
+	}
+	if !strings.Contains(got.String(), "piped-input") {
+		t.Fatalf("expected echoed input, got %q", got.String())
+	}
+}
+
+func TestRemoteCmdStdinEOFAndDroppedConnection(t *testing.T) {
+	var mu sync.Mutex
+	var inputs []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := fakeContext("ctx-1", true)
+		if req["pty_size"] != nil {
+			data["id"] = "ctx-pty"
+		}
+		writeFakeSuccess(w, http.StatusCreated, data)
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			// Input ends once nothing more arrives for a moment.
+			_ = conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+			var req sandbox0.ContextWebSocketRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				break
+			}
+			mu.Lock()
+			inputs = append(inputs, req.Data)
+			mu.Unlock()
+		}
+		if r.PathValue("ctx") == "ctx-pty" {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+		// The connection drops without a close frame.
+		_ = conn.NetConn().Close()
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"deleted": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, pty := range []bool{false, true} {
+		mu.Lock()
+		inputs = nil
+		mu.Unlock()
+		cmd := client.Sandbox("sb-fake").CommandContext(ctx, "cat")
+		cmd.Stdin = strings.NewReader("line\n")
+		want := []string{"line\n"}
+		if pty {
+			cmd.PTYSize = &apispec.PTYSize{Rows: apispec.NewOptInt32(24), Cols: apispec.NewOptInt32(80)}
+			want = append(want, "\x04")
+		}
+		err := cmd.Run()
+		mu.Lock()
+		got := slices.Clone(inputs)
+		mu.Unlock()
+		if !slices.Equal(got, want) {
+			t.Fatalf("pty=%v: expected inputs %q, got %q", pty, want, got)
+		}
+		if pty && err != nil {
+			t.Fatalf("expected a closed connection to end the command cleanly, got %v", err)
+		}
+		var exitErr *sandbox0.ExitError
+		if !pty && (err == nil || errors.As(err, &exitErr)) {
+			t.Fatalf("expected a dropped connection to fail the command, got %v", err)
+		}
+	}
+}
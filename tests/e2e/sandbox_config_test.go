@@ -0,0 +1,142 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func TestSandboxConfigBuilderValidation(t *testing.T) {
+	_, err := sandbox0.NewSandboxConfigBuilder().
+		TTL(600).
+		HardTTL(300).
+		WebhookWatchDir("/workspace").
+		ExposePort(70000, false).
+		Build()
+	var cfgErr *sandbox0.SandboxConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected SandboxConfigError, got %v", err)
+	}
+	if len(cfgErr.Issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d: %v", len(cfgErr.Issues), err)
+	}
+
+	if _, err := sandbox0.NewSandboxConfigBuilder().TTL(-1).Build(); err == nil {
+		t.Fatalf("expected negative ttl to be rejected")
+	}
+
+	config, err := sandbox0.NewSandboxConfigBuilder().
+		TTL(300).
+		HardTTL(600).
+		EnvVar("A", "1").
+		Webhook("https://example.com/webhook", "secret").
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	next := config
+	next.TTL = apispec.NewOptInt32(400)
+	diff, changed := sandbox0.DiffSandboxConfig(config, next)
+	if !changed {
+		t.Fatalf("expected diff to report a change")
+	}
+	if !diff.TTL.IsSet() || diff.HardTTL.IsSet() || diff.EnvVars.IsSet() || diff.Webhook.IsSet() {
+		t.Fatalf("expected diff to contain only ttl, got %+v", diff)
+	}
+	if _, changed := sandbox0.DiffSandboxConfig(config, config); changed {
+		t.Fatalf("expected identical configs to produce no diff")
+	}
+}
+
+func TestClaimSandboxRejectsInvalidConfig(t *testing.T) {
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL("http://127.0.0.1:1"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = client.ClaimSandbox(ctx, "default", sandbox0.WithSandboxTTL(600), sandbox0.WithSandboxHardTTL(60))
+	var cfgErr *sandbox0.SandboxConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected SandboxConfigError before network call, got %v", err)
+	}
+}
+
+func TestUpdateSandboxConfig(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+
+	initial, err := sandbox0.NewSandboxConfigBuilder().
+		TTL(300).
+		HardTTL(600).
+		EnvVars(map[string]string{"SDK_E2E": "true"}).
+		Build()
+	if err != nil {
+		t.Fatalf("build config failed: %v", err)
+	}
+	sandbox := claimSandbox(t, client, cfg, sandbox0.WithSandboxConfig(initial))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	next := initial
+	next.AutoResume = apispec.NewOptBool(false)
+	updated, err := client.UpdateSandboxConfig(ctx, sandbox.ID, initial, next)
+	if err != nil {
+		t.Fatalf("update sandbox config failed: %v", err)
+	}
+	if updated.GetAutoResume() {
+		t.Fatalf("expected sandbox auto_resume to be false after update")
+	}
+}
+
+func TestUpdateSandboxValidatesPartialConfig(t *testing.T) {
+	var updates int
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v1/sandboxes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		updates++
+		now := time.Now().UTC().Format(time.RFC3339)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "data": map[string]any{
+			"id": r.PathValue("id"), "template_id": "default", "team_id": "team-1", "status": "running",
+			"paused": false, "auto_resume": true, "pod_name": "pod-1",
+			"expires_at": now, "claimed_at": now, "created_at": now,
+		}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Rotating the secret alone leaves the webhook URL unchanged.
+	rotate := apispec.SandboxConfig{Webhook: apispec.NewOptWebhookConfig(apispec.WebhookConfig{Secret: apispec.NewOptString("rotated")})}
+	if _, err := client.UpdateSandbox(ctx, "sb-1", apispec.SandboxUpdateRequest{Config: apispec.NewOptSandboxConfig(rotate)}); err != nil {
+		t.Fatalf("partial update failed: %v", err)
+	}
+	invalid := apispec.SandboxConfig{TTL: apispec.NewOptInt32(-1)}
+	_, err = client.UpdateSandbox(ctx, "sb-1", apispec.SandboxUpdateRequest{Config: apispec.NewOptSandboxConfig(invalid)})
+	var cfgErr *sandbox0.SandboxConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected SandboxConfigError, got %v", err)
+	}
+	if updates != 1 {
+		t.Fatalf("expected one update call, got %d", updates)
+	}
+}
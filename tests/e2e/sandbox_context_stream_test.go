@@ -0,0 +1,374 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/apispec"
+)
+
+func TestSandboxContextStream(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ctxResp, err := sandbox.CreateContext(ctx, apispec.CreateContextRequest{
+		Type: apispec.NewOptProcessType(apispec.ProcessTypeRepl),
+		Repl: apispec.NewOptCreateREPLContextRequest(apispec.CreateREPLContextRequest{
+			Language: apispec.NewOptString("python"),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("create repl context failed: %v", err)
+	}
+	defer sandbox.DeleteContext(ctx, ctxResp.ID)
+
+	stream, err := sandbox.OpenStream(ctx, ctxResp.ID)
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	result, err := stream.ExecStream(ctx, "print('stream-exec')\n")
+	if err != nil {
+		t.Fatalf("exec stream failed: %v", err)
+	}
+	if !strings.Contains(result.OutputRaw, "stream-exec") {
+		t.Fatalf("unexpected exec output: %q", result.OutputRaw)
+	}
+
+	requestID, err := stream.SendInput("print('stream-input')\n")
+	if err != nil {
+		t.Fatalf("send input failed: %v", err)
+	}
+	var output strings.Builder
+	for msg, err := range stream.Messages() {
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		switch msg := msg.(type) {
+		case sandbox0.StreamOutput:
+			output.WriteString(msg.Data)
+		case sandbox0.StreamDone:
+			if msg.RequestID != requestID {
+				continue
+			}
+			if !strings.Contains(output.String(), "stream-input") {
+				t.Fatalf("unexpected output before done: %q", output.String())
+			}
+			return
+		}
+	}
+	t.Fatalf("stream ended before done for %s", requestID)
+}
+
+// reconnectServer is a context WebSocket that replays its output to each new
+// connection, like the server does, and can drop connections.
+type reconnectServer struct {
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	output   string
+	requests []string
+	swallow  map[string]bool
+	gone     bool
+	// unavailable makes dials fail with a retryable status.
+	unavailable bool
+}
+
+func (f *reconnectServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	gone, unavailable := f.gone, f.unavailable
+	f.mu.Unlock()
+	if unavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if gone {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"success":false,"error":{"code":"not_found","message":"context not found"}}`))
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	f.conn = conn
+	replay := f.output
+	f.mu.Unlock()
+	if replay != "" {
+		_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: replay})
+	}
+	for {
+		var req sandbox0.ContextWebSocketRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req.RequestID)
+		swallow := f.swallow[req.Data]
+		delete(f.swallow, req.Data)
+		out := "echo:" + req.Data
+		if !swallow {
+			f.output += out
+		}
+		f.mu.Unlock()
+		if swallow {
+			// The connection drops before the input is answered.
+			_ = conn.Close()
+			return
+		}
+		_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: out})
+		_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageDone, RequestID: req.RequestID})
+	}
+}
+
+// drop closes the connection after output was produced that nobody received.
+// With reset, the server keeps only that output, as if its buffer wrapped.
+func (f *reconnectServer) drop(output string, reset bool) {
+	f.mu.Lock()
+	if reset {
+		f.output = ""
+	}
+	f.output += output
+	conn := f.conn
+	f.mu.Unlock()
+	_ = conn.Close()
+}
+
+func TestContextStreamReconnects(t *testing.T) {
+	fake := &reconnectServer{swallow: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", fake.serve)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	sandbox := client.Sandbox("sb-1")
+
+	var mu sync.Mutex
+	var states []sandbox0.StreamState
+	stream, err := sandbox.OpenStream(context.Background(), "ctx-1",
+		sandbox0.WithStreamReconnect(0, 10*time.Millisecond),
+		sandbox0.WithStreamKeepalive(time.Second, 5*time.Second),
+		sandbox0.WithStreamStateHandler(func(event sandbox0.StreamStateEvent) {
+			mu.Lock()
+			states = append(states, event.State)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	type item struct {
+		msg sandbox0.StreamMessage
+		err error
+	}
+	items := make(chan item, 64)
+	go func() {
+		defer close(items)
+		for msg, err := range stream.Messages() {
+			items <- item{msg, err}
+		}
+	}()
+	next := func() item {
+		t.Helper()
+		select {
+		case it, ok := <-items:
+			if !ok {
+				t.Fatalf("stream ended early: %v", stream.Err())
+			}
+			return it
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for a message")
+			return item{}
+		}
+	}
+	expectOutput := func(want string) {
+		t.Helper()
+		if it := next(); it.msg != (sandbox0.StreamOutput{Source: sandbox0.OutputSourceStdout, Data: want}) {
+			t.Fatalf("expected output %q, got %#v (%v)", want, it.msg, it.err)
+		}
+	}
+	expectDone := func(requestID string) {
+		t.Helper()
+		if it := next(); it.msg == nil || it.msg.(sandbox0.StreamDone).RequestID != requestID {
+			t.Fatalf("expected done for %s, got %#v (%v)", requestID, it.msg, it.err)
+		}
+	}
+
+	requestID, err := stream.SendInput("a\n")
+	if err != nil {
+		t.Fatalf("send input failed: %v", err)
+	}
+	expectOutput("echo:a\n")
+	expectDone(requestID)
+
+	// Only output produced while disconnected is delivered from the replay.
+	fake.drop("offline\n", false)
+	expectOutput("offline\n")
+
+	sent := func(requestID string) int {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(slices.DeleteFunc(slices.Clone(fake.requests), func(id string) bool { return id != requestID }))
+	}
+
+	// Input that was written but not answered before the drop is reported,
+	// not sent again.
+	fake.mu.Lock()
+	fake.swallow["b\n"] = true
+	fake.mu.Unlock()
+	requestID, err = stream.SendInput("b\n")
+	if err != nil {
+		t.Fatalf("send input failed: %v", err)
+	}
+	if it := next(); it.msg == nil || it.msg.(sandbox0.StreamInputUnconfirmed).RequestID != requestID {
+		t.Fatalf("expected unconfirmed input %s, got %#v (%v)", requestID, it.msg, it.err)
+	}
+	if n := sent(requestID); n != 1 {
+		t.Fatalf("expected input to be sent once, got %d", n)
+	}
+
+	// Input sent while reconnecting is queued and sent once connected.
+	fake.mu.Lock()
+	fake.unavailable = true
+	fake.mu.Unlock()
+	fake.drop("", false)
+	for stream.State() != sandbox0.StreamReconnecting {
+		time.Sleep(time.Millisecond)
+	}
+	requestID, err = stream.SendInput("c\n")
+	if err != nil {
+		t.Fatalf("send input failed: %v", err)
+	}
+	fake.mu.Lock()
+	fake.unavailable = false
+	fake.mu.Unlock()
+	expectOutput("echo:c\n")
+	expectDone(requestID)
+	if n := sent(requestID); n != 1 {
+		t.Fatalf("expected queued input to be sent once, got %d", n)
+	}
+
+	// A replay that does not continue the received output is marked as a gap.
+	fake.drop("fresh\n", true)
+	if it := next(); it.msg == nil {
+		t.Fatalf("expected gap, got error %v", it.err)
+	} else if _, ok := it.msg.(sandbox0.StreamGap); !ok {
+		t.Fatalf("expected gap, got %#v", it.msg)
+	}
+	expectOutput("fresh\n")
+
+	// The stream ends once the context is gone.
+	fake.mu.Lock()
+	fake.gone = true
+	fake.mu.Unlock()
+	fake.drop("", false)
+	it := next()
+	var apiErr *sandbox0.APIError
+	if !errors.As(it.err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found error, got %#v (%v)", it.msg, it.err)
+	}
+	<-stream.Done()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if states[0] != sandbox0.StreamConnected || states[len(states)-1] != sandbox0.StreamClosed ||
+		!slices.Contains(states, sandbox0.StreamReconnecting) {
+		t.Fatalf("unexpected states: %v", states)
+	}
+	if stream.State() != sandbox0.StreamClosed {
+		t.Fatalf("unexpected final state: %s", stream.State())
+	}
+}
+
+func TestContextStreamBacklogGapAndServerClose(t *testing.T) {
+	const sent = 4100
+	closed := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for i := range sent {
+			_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: strconv.Itoa(i)})
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		// The client answers the close frame and then drops the connection.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		_ = conn.NetConn().SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.NetConn().Read(make([]byte, 1))
+		closed <- err
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.Sandbox("sb-1").OpenStream(ctx, "ctx-1")
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	select {
+	case <-stream.Done():
+	case <-ctx.Done():
+		t.Fatalf("stream did not end after the server closed it")
+	}
+	if err := <-closed; !errors.Is(err, io.EOF) {
+		t.Fatalf("expected the client to close the connection, got %v", err)
+	}
+
+	var got []sandbox0.StreamMessage
+	for msg, err := range stream.Messages() {
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		got = append(got, msg)
+	}
+	gap, ok := got[0].(sandbox0.StreamGap)
+	if !ok || !errors.Is(gap.Err, sandbox0.ErrStreamBacklogFull) {
+		t.Fatalf("expected a backlog gap first, got %#v", got[0])
+	}
+	last, ok := got[len(got)-1].(sandbox0.StreamOutput)
+	if !ok || last.Data != strconv.Itoa(sent-1) {
+		t.Fatalf("expected the newest output last, got %#v", got[len(got)-1])
+	}
+	if len(got) >= sent {
+		t.Fatalf("expected old messages to be dropped, got %d", len(got))
+	}
+}
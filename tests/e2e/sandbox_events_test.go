@@ -0,0 +1,223 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+	"github.com/sandbox0-ai/sdk-go/pkg/sandbox0webhook"
+)
+
+func TestSandboxEventsFromWebhook(t *testing.T) {
+	const secret = "webhook-secret"
+	broker := sandbox0webhook.NewBroker()
+	server := httptest.NewServer(sandbox0webhook.NewHandler(secret, sandbox0webhook.WithBroker(broker)))
+	defer server.Close()
+
+	_, client := newFakeEventsAPI(t)
+	sandbox := client.Sandbox("sb-events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := sandbox.Events(ctx, sandbox0.EventFilter{
+		Webhook: broker,
+		Types:   []sandbox0.SandboxEventType{sandbox0.SandboxEventProcessCrashed, sandbox0.SandboxEventPaused},
+	})
+	if err != nil {
+		t.Fatalf("events failed: %v", err)
+	}
+
+	other := sandbox0webhook.NewSimulator(server.URL, secret, sandbox0webhook.WithSimulatorSandboxID("sb-other"))
+	sim := sandbox0webhook.NewSimulator(server.URL, secret, sandbox0webhook.WithSimulatorSandboxID("sb-events"))
+	go func() {
+		_, _ = other.Run(ctx, []sandbox0webhook.Step{sandbox0webhook.SandboxPausedStep()})
+		_, _ = sim.Run(ctx, []sandbox0webhook.Step{
+			sandbox0webhook.ProcessStartedStep("ctx-1"),
+			sandbox0webhook.ProcessCrashedStep("ctx-1", 3),
+			sandbox0webhook.SandboxPausedStep(),
+		})
+	}()
+
+	want := []sandbox0.SandboxEventType{sandbox0.SandboxEventProcessCrashed, sandbox0.SandboxEventPaused}
+	for _, expected := range want {
+		select {
+		case event := <-events:
+			if event.Type != expected {
+				t.Fatalf("expected %s, got %s", expected, event.Type)
+			}
+			if event.SandboxID != "sb-events" || event.Source != sandbox0.SandboxEventSourceWebhook {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+			if expected == sandbox0.SandboxEventProcessCrashed && (event.ExitCode == nil || *event.ExitCode != 3) {
+				t.Fatalf("expected exit code 3, got %+v", event.ExitCode)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+}
+
+// fakeEventsAPI serves the sandbox and context state polled by Events.
+type fakeEventsAPI struct {
+	mu       sync.Mutex
+	status   string
+	paused   bool
+	contexts map[string]bool
+	lists    int
+}
+
+func newFakeEventsAPI(t *testing.T) (*fakeEventsAPI, *sandbox0.Client) {
+	t.Helper()
+	api := &fakeEventsAPI{status: "running", contexts: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UTC().Format(time.RFC3339)
+		api.mu.Lock()
+		status, paused := api.status, api.paused
+		api.mu.Unlock()
+		writeFakeSuccess(w, http.StatusOK, map[string]any{
+			"id": r.PathValue("id"), "template_id": "default", "team_id": "team-1", "status": status,
+			"paused": paused, "auto_resume": true, "pod_name": "pod-1",
+			"expires_at": now, "claimed_at": now, "created_at": now,
+		})
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.lists++
+		contexts := []map[string]any{}
+		for id, running := range api.contexts {
+			contexts = append(contexts, fakeContext(id, running))
+		}
+		api.mu.Unlock()
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"contexts": contexts})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	return api, client
+}
+
+func (api *fakeEventsAPI) update(fn func()) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	fn()
+}
+
+// awaitPolls waits until the contexts have been listed n more times.
+func (api *fakeEventsAPI) awaitPolls(t *testing.T, n int) {
+	t.Helper()
+	api.mu.Lock()
+	target := api.lists + n
+	api.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		api.mu.Lock()
+		lists := api.lists
+		api.mu.Unlock()
+		if lists >= target {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d polls", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSandboxEventsFromFakePolling(t *testing.T) {
+	const secret = "webhook-secret"
+	broker := sandbox0webhook.NewBroker()
+	server := httptest.NewServer(sandbox0webhook.NewHandler(secret, sandbox0webhook.WithBroker(broker)))
+	defer server.Close()
+
+	api, client := newFakeEventsAPI(t)
+	api.update(func() { api.contexts["ctx-1"] = true })
+	sandbox := client.Sandbox("sb-events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := sandbox.Events(ctx, sandbox0.EventFilter{Webhook: broker, PollInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("events failed: %v", err)
+	}
+	api.awaitPolls(t, 1)
+	next := func() sandbox0.SandboxEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Type == sandbox0.SandboxEventError {
+				t.Fatalf("unexpected error event: %v", event.Err)
+			}
+			return event
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for an event")
+		}
+		return sandbox0.SandboxEvent{}
+	}
+
+	// The webhook reports the exit first; polling must not report it again.
+	sim := sandbox0webhook.NewSimulator(server.URL, secret, sandbox0webhook.WithSimulatorSandboxID("sb-events"))
+	if _, err := sim.Run(ctx, []sandbox0webhook.Step{sandbox0webhook.ProcessExitedStep("ctx-1")}); err != nil {
+		t.Fatalf("simulate exit failed: %v", err)
+	}
+	if event := next(); event.Type != sandbox0.SandboxEventProcessExited || event.Source != sandbox0.SandboxEventSourceWebhook || event.ContextID != "ctx-1" {
+		t.Fatalf("expected webhook exit of ctx-1, got %+v", event)
+	}
+	api.update(func() { api.contexts["ctx-1"] = false })
+	api.awaitPolls(t, 2)
+
+	// Changes only polling sees are still reported.
+	api.update(func() { api.contexts["ctx-2"] = true })
+	if event := next(); event.Type != sandbox0.SandboxEventProcessStarted || event.Source != sandbox0.SandboxEventSourcePoll || event.ContextID != "ctx-2" {
+		t.Fatalf("expected polled start of ctx-2, got %+v", event)
+	}
+	api.update(func() { api.status, api.paused = "paused", true })
+	if event := next(); event.Type != sandbox0.SandboxEventStatusChanged || event.Status != "paused" {
+		t.Fatalf("expected status change, got %+v", event)
+	}
+	if event := next(); event.Type != sandbox0.SandboxEventPaused || event.Source != sandbox0.SandboxEventSourcePoll {
+		t.Fatalf("expected polled pause, got %+v", event)
+	}
+}
+
+func TestSandboxEventsPolling(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	events, err := sandbox.Events(ctx, sandbox0.EventFilter{
+		Types:        []sandbox0.SandboxEventType{sandbox0.SandboxEventPaused},
+		PollInterval: 500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("events failed: %v", err)
+	}
+	time.Sleep(time.Second)
+	if _, err := client.PauseSandbox(ctx, sandbox.ID); err != nil {
+		t.Fatalf("pause sandbox failed: %v", err)
+	}
+	for {
+		select {
+		case event := <-events:
+			if event.Type == sandbox0.SandboxEventPaused {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for paused event")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxExecAndShell(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	untrusted := "it's $HOME; rm -rf /"
+	result, err := sandbox.Exec(ctx, []string{"printf", "%s", untrusted})
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if result.Stdout != untrusted {
+		t.Fatalf("argv not passed as is: %q", result.Stdout)
+	}
+	_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+
+	result, err = sandbox.Exec(ctx, sandbox0.Shell("printf '%s' "+sandbox0.ShellQuote(untrusted)+" | tr a-z A-Z"))
+	if err != nil {
+		t.Fatalf("exec shell failed: %v", err)
+	}
+	if result.Stdout != "IT'S $HOME; RM -RF /" {
+		t.Fatalf("unexpected shell output: %q", result.Stdout)
+	}
+	_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+
+	result, err = sandbox.Exec(ctx, sandbox0.Shell("exit 4"), sandbox0.WithCmdExitStatus(true))
+	var exitErr *sandbox0.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 4 {
+		t.Fatalf("expected exit code 4, got %v", err)
+	}
+	_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+}
+
+func TestCmdRejectsShellSyntax(t *testing.T) {
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL("http://127.0.0.1:1"), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	sandbox := client.Sandbox("sb-1")
+	ctx := context.Background()
+
+	for _, cmd := range []string{"echo a | wc -l", "make && make install", "cd /tmp ; ls", "cmd > out.txt", "cmd 2>&1", "sleep 1 &"} {
+		if _, err := sandbox.Cmd(ctx, cmd); !errors.Is(err, sandbox0.ErrShellSyntax) {
+			t.Fatalf("expected shell syntax error for %q, got %v", cmd, err)
+		}
+	}
+	// Characters inside words and quoted operators are left to the program.
+	for _, cmd := range []string{"curl https://h/p?a=1&b=2", "python3 -c print(1)", "ls foo*", "echo '|' \\; \"&&\"", "echo a # | b"} {
+		if _, err := sandbox.Cmd(ctx, cmd); errors.Is(err, sandbox0.ErrShellSyntax) {
+			t.Fatalf("unexpected shell syntax error for %q: %v", cmd, err)
+		}
+	}
+	if _, err := sandbox.Exec(ctx, nil); err == nil {
+		t.Fatalf("expected error for empty argv")
+	}
+
+	got := sandbox0.ShellQuote("plain", "", "a b", "it's", "$x")
+	if want := `plain '' 'a b' 'it'\''s' '$x'`; got != want {
+		t.Fatalf("unexpected quoting: %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxJobs(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	job, err := sandbox.StartJob(ctx, "sh -c 'for i in 1 2 3; do echo line$i; sleep 0.2; done; exit 2'", sandbox0.WithCmdExitStatus(true))
+	if err != nil {
+		t.Fatalf("start job failed: %v", err)
+	}
+	defer job.Delete(ctx)
+
+	var followed strings.Builder
+	var followErr error
+	for chunk, err := range job.Follow(ctx) {
+		if err != nil {
+			followErr = err
+			continue
+		}
+		followed.WriteString(chunk.Data)
+	}
+	var exitErr *sandbox0.ExitError
+	if !errors.As(followErr, &exitErr) || exitErr.ExitCode != 2 {
+		t.Fatalf("expected exit error with code 2, got %v", followErr)
+	}
+	if !strings.Contains(followed.String(), "line3") {
+		t.Fatalf("unexpected followed output: %q", followed.String())
+	}
+	lines, err := job.Tail(ctx, 1)
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "line3" {
+		t.Fatalf("unexpected tail: %q", lines)
+	}
+	status, err := job.Status(ctx)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if status.State != sandbox0.JobExited || status.ExitCode != 2 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	daemon, err := sandbox.StartJob(ctx, "sh -c 'while true; do echo tick; sleep 0.2; done'", sandbox0.WithJobAutoDelete())
+	if err != nil {
+		t.Fatalf("start daemon failed: %v", err)
+	}
+	if _, err := daemon.Stats(ctx); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+
+	// A fresh Sandbox handle discovers the running daemon.
+	jobs, err := client.Sandbox(sandbox.ID).Jobs(ctx)
+	if err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	var discovered *sandbox0.Job
+	for _, candidate := range jobs {
+		if candidate.ContextID == daemon.ContextID {
+			discovered = candidate
+		}
+	}
+	if discovered == nil {
+		t.Fatalf("daemon %s not listed in %d jobs", daemon.ContextID, len(jobs))
+	}
+
+	if err := daemon.Kill(ctx); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	_ = daemon.Wait(ctx)
+	jobs, err = sandbox.Jobs(ctx)
+	if err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	for _, candidate := range jobs {
+		if candidate.ContextID == daemon.ContextID {
+			t.Fatalf("auto-deleted daemon %s still listed", daemon.ContextID)
+		}
+	}
+}
+
+func TestJobCloseStopsPolling(t *testing.T) {
+	var polls, deletes atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeSuccess(w, http.StatusCreated, fakeContext("ctx-daemon", true))
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		// The connection drops while the daemon keeps running.
+		_ = conn.Close()
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		writeFakeSuccess(w, http.StatusOK, fakeContext(r.PathValue("ctx"), true))
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		deletes.Add(1)
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"deleted": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := client.Sandbox("sb-1").StartJob(ctx, "daemon --serve", sandbox0.WithCmdTTL(60), sandbox0.WithJobAutoDelete())
+	if err != nil {
+		t.Fatalf("start job failed: %v", err)
+	}
+	for polls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := job.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	// Wait returns once the background polling stopped.
+	if err := job.Wait(ctx); !errors.Is(err, sandbox0.ErrJobClosed) {
+		t.Fatalf("expected ErrJobClosed, got %v", err)
+	}
+	if deletes.Load() != 0 {
+		t.Fatalf("closed job deleted its context")
+	}
+}
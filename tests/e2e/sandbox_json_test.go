@@ -0,0 +1,94 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxRunJSONAndCmdJSON(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	defer sandbox.Close()
+
+	type stats struct {
+		Count int      `json:"count"`
+		Names []string `json:"names"`
+	}
+
+	got, err := sandbox0.RunJSON[stats](ctx, sandbox, "python",
+		"import json, os\nprint('warming up {')\njson.dump({'count': 2, 'names': ['a', 'b']}, open(os.environ['SANDBOX0_RESULT_FILE'], 'w'))\n")
+	if err != nil {
+		t.Fatalf("run json failed: %v", err)
+	}
+	if got.Count != 2 || len(got.Names) != 2 {
+		t.Fatalf("unexpected run json result: %+v", got)
+	}
+
+	got, err = sandbox0.CmdJSON[stats](ctx, sandbox, `/bin/sh -c 'echo noise; echo "{\"count\": 3}"; echo done'`)
+	if err != nil {
+		t.Fatalf("cmd json failed: %v", err)
+	}
+	if got.Count != 3 {
+		t.Fatalf("unexpected cmd json result: %+v", got)
+	}
+
+	_, err = sandbox0.CmdJSON[stats](ctx, sandbox, `/bin/sh -c 'echo "{\"count\": \"many\"}" > "$SANDBOX0_RESULT_FILE"; echo raw-output'`)
+	var decodeErr *sandbox0.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+	if !strings.Contains(decodeErr.Data, "many") || !strings.Contains(decodeErr.Output, "raw-output") {
+		t.Fatalf("decode error missing data or output: %+v", decodeErr)
+	}
+}
+
+func TestRunJSONPreludeAndCleanup(t *testing.T) {
+	api, client := newFakeContextAPI(t, func(input string) string {
+		if strings.Contains(input, "throw") {
+			return "Uncaught Error: boom\r\n    at REPL1:1:7\r\n> "
+		}
+		return `{"count": 1}` + "\r\n> "
+	})
+	sandbox := client.Sandbox("sb-fake")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	got, err := sandbox0.RunJSON[map[string]int](ctx, sandbox, "node", "console.log(JSON.stringify({count: 1}))")
+	if err != nil {
+		t.Fatalf("run json failed: %v", err)
+	}
+	if got["count"] != 1 {
+		t.Fatalf("unexpected run json result: %v", got)
+	}
+	api.mu.Lock()
+	input := api.execData[len(api.execData)-1]
+	api.mu.Unlock()
+	prelude, code, ok := strings.Cut(input, "; ")
+	if !ok || strings.Contains(prelude, "\n") || !strings.HasPrefix(prelude, "process.env."+sandbox0.ResultFileEnv+" = '/tmp/") ||
+		!strings.HasPrefix(code, "console.log(") {
+		t.Fatalf("expected the prelude on the first line of the code, got %q", input)
+	}
+
+	_, err = sandbox0.RunJSON[map[string]int](ctx, sandbox, "node", "throw new Error('boom')", sandbox0.WithRunFailOnError())
+	var runErr *sandbox0.RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("expected run error, got %v", err)
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.removed) != 1 || !strings.HasPrefix(api.removed[0], "/tmp/sandbox0-result-") {
+		t.Fatalf("expected the result file to be removed after the failed run, got %v", api.removed)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxCmdOutputLimit(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "output.log")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create output file failed: %v", err)
+	}
+	defer file.Close()
+
+	result, err := sandbox.Exec(ctx, sandbox0.Shell("seq 1 20000"),
+		sandbox0.WithCmdMaxOutputBytes(1000),
+		sandbox0.WithCmdOutputSink(file),
+	)
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	defer sandbox.DeleteContext(ctx, result.ContextID)
+	if !result.Truncated || !strings.HasPrefix(result.Stdout, "1\n2\n") ||
+		!strings.HasSuffix(result.Stdout, "19999\n20000\n") || !strings.Contains(result.Stdout, "bytes truncated") {
+		t.Fatalf("unexpected truncated output: %q", result.Stdout)
+	}
+	if len(result.Stdout) > 1100 {
+		t.Fatalf("output not limited: %d bytes", len(result.Stdout))
+	}
+
+	spilled, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output file failed: %v", err)
+	}
+	if lines := strings.Count(string(spilled), "\n"); lines != 20000 {
+		t.Fatalf("expected full output in sink, got %d lines", lines)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRunOutputLimit(t *testing.T) {
+	raw := "hé" + strings.Repeat("x", 1000) + "éd"
+	_, client := newFakeContextAPI(t, func(string) string { return raw })
+	sandbox := client.Sandbox("sb-fake")
+	ctx := context.Background()
+
+	var sink bytes.Buffer
+	result, err := sandbox.Run(ctx, "python", "print('x' * 1000)\n",
+		sandbox0.WithMaxOutputBytes(4),
+		sandbox0.WithOutputSink(&sink),
+	)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if sink.String() != raw {
+		t.Fatalf("sink did not receive the full output: %d bytes", sink.Len())
+	}
+	// The first and last two bytes are kept, without the halves of é.
+	want := "h\n[... 1004 bytes truncated ...]\nd"
+	if !result.Truncated || result.OutputRaw != want {
+		t.Fatalf("unexpected truncated output: %q, want %q", result.OutputRaw, want)
+	}
+
+	result, err = sandbox.Run(ctx, "python", "print(1)\n", sandbox0.WithMaxOutputBytes(len(raw)))
+	if err != nil || result.Truncated || result.OutputRaw != raw {
+		t.Fatalf("output within the limit was changed: %v %q", err, result.OutputRaw)
+	}
+
+	result, err = sandbox.Run(ctx, "python", "print(1)\n", sandbox0.WithOutputSink(failingWriter{}))
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected sink error, got %v", err)
+	}
+	if result.OutputRaw != raw {
+		t.Fatalf("result missing after sink error: %q", result.OutputRaw)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxRunHealsREPLContext(t *testing.T) {
+	api, client := newFakeContextAPI(t, func(string) string { return ">>> 1\r\n" })
+	sandbox := client.Sandbox("sb-heal")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, err := sandbox.Run(ctx, "python", "1\n")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	// A deleted or expired context is replaced transparently.
+	api.drop(first.ContextID)
+	second, err := sandbox.Run(ctx, "python", "1\n")
+	if err != nil {
+		t.Fatalf("run after delete failed: %v", err)
+	}
+	if second.ContextID == first.ContextID {
+		t.Fatalf("expected a new context after delete")
+	}
+
+	// A context that stopped running is restarted in place, but the failed
+	// input, which may have stopped it, is not run again.
+	api.setRunning(second.ContextID, false)
+	if _, err := sandbox.Run(ctx, "python", "exit()\n"); err == nil {
+		t.Fatalf("expected the exec error for a stopped context")
+	}
+	if n := api.execCount("exit()\n"); n != 1 {
+		t.Fatalf("expected input to be sent once, got %d", n)
+	}
+	third, err := sandbox.Run(ctx, "python", "1\n")
+	if err != nil {
+		t.Fatalf("run after stop failed: %v", err)
+	}
+	if third.ContextID != second.ContextID || api.restarts != 1 {
+		t.Fatalf("expected restart of %s, got %s (restarts %d)", second.ContextID, third.ContextID, api.restarts)
+	}
+}
+
+func TestSandboxRunCacheKeyedByOptions(t *testing.T) {
+	api, client := newFakeContextAPI(t, func(string) string { return "" })
+	sandbox := client.Sandbox("sb-cache")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	run := func(opts ...sandbox0.RunOption) string {
+		t.Helper()
+		result, err := sandbox.Run(ctx, "python", "1\n", opts...)
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return result.ContextID
+	}
+	plain := run()
+	inTmp := run(sandbox0.WithCWD("/tmp"))
+	if plain == inTmp {
+		t.Fatalf("expected separate contexts for different cwd")
+	}
+	if again := run(sandbox0.WithCWD("/tmp")); again != inTmp {
+		t.Fatalf("expected cached context %s, got %s", inTmp, again)
+	}
+	withEnv := run(sandbox0.WithEnvVars(map[string]string{"A": "1"}))
+	node := ""
+	if result, err := sandbox.Run(ctx, "node", "1\n"); err != nil {
+		t.Fatalf("run node failed: %v", err)
+	} else {
+		node = result.ContextID
+	}
+
+	if err := sandbox.ResetREPL(ctx, "python"); err != nil {
+		t.Fatalf("reset repl failed: %v", err)
+	}
+	for _, id := range []string{plain, inTmp, withEnv} {
+		if _, ok := api.state(id); ok {
+			t.Fatalf("expected %s to be deleted by ResetREPL", id)
+		}
+	}
+	if _, ok := api.state(node); !ok {
+		t.Fatalf("ResetREPL deleted the node context")
+	}
+	if fresh := run(); fresh == plain {
+		t.Fatalf("expected a fresh context after reset")
+	}
+
+	if err := sandbox.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, ok := api.state(node); ok {
+		t.Fatalf("expected Close to delete the node context")
+	}
+}
@@ -0,0 +1,291 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+// fakeContextAPI serves the context endpoints used by Run with canned REPL output.
+type fakeContextAPI struct {
+	mu       sync.Mutex
+	created  []map[string]any
+	execData []string
+	running  map[string]bool
+	deleted  []string
+	removed  []string
+	restarts int
+	output   func(input string) string
+}
+
+func newFakeContextAPI(t *testing.T, output func(input string) string) (*fakeContextAPI, *sandbox0.Client) {
+	t.Helper()
+	api := &fakeContextAPI{output: output, running: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		api.mu.Lock()
+		api.created = append(api.created, req)
+		id := fmt.Sprintf("ctx-%d", len(api.created))
+		api.running[id] = true
+		api.mu.Unlock()
+		writeFakeSuccess(w, http.StatusCreated, fakeContext(id, true))
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		running, ok := api.state(r.PathValue("ctx"))
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "context not found")
+			return
+		}
+		writeFakeSuccess(w, http.StatusOK, fakeContext(r.PathValue("ctx"), running))
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("ctx")
+		api.mu.Lock()
+		_, ok := api.running[id]
+		delete(api.running, id)
+		api.deleted = append(api.deleted, id)
+		api.mu.Unlock()
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "context not found")
+			return
+		}
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"deleted": true})
+	})
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts/{ctx}/restart", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("ctx")
+		api.mu.Lock()
+		_, ok := api.running[id]
+		if ok {
+			api.running[id] = true
+			api.restarts++
+		}
+		api.mu.Unlock()
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "context not found")
+			return
+		}
+		writeFakeSuccess(w, http.StatusOK, fakeContext(id, true))
+	})
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts/{ctx}/exec", func(w http.ResponseWriter, r *http.Request) {
+		running, ok := api.state(r.PathValue("ctx"))
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "context not found")
+			return
+		}
+		var req struct {
+			Data string `json:"data"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		api.mu.Lock()
+		api.execData = append(api.execData, req.Data)
+		api.mu.Unlock()
+		if !running {
+			writeFakeError(w, http.StatusConflict, "context not running")
+			return
+		}
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"output_raw": api.output(req.Data)})
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeError(w, http.StatusNotFound, "file not found")
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.removed = append(api.removed, r.URL.Query().Get("path"))
+		api.mu.Unlock()
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"deleted": true})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	return api, client
+}
+
+func (api *fakeContextAPI) state(id string) (running, ok bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	running, ok = api.running[id]
+	return running, ok
+}
+
+func (api *fakeContextAPI) setRunning(id string, running bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.running[id] = running
+}
+
+func (api *fakeContextAPI) drop(id string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	delete(api.running, id)
+}
+
+func (api *fakeContextAPI) execCount(data string) int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	n := 0
+	for _, sent := range api.execData {
+		if sent == data {
+			n++
+		}
+	}
+	return n
+}
+
+func (api *fakeContextAPI) lastCreated() map[string]any {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.created) == 0 {
+		return nil
+	}
+	return api.created[len(api.created)-1]
+}
+
+func fakeContext(id string, running bool) map[string]any {
+	return map[string]any{
+		"id": id, "type": "repl", "running": running, "paused": false,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"success": false,
+		"error":   map[string]any{"code": "error", "message": message},
+	})
+}
+
+func writeFakeSuccess(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "data": data})
+}
+
+func TestSandboxRunParsesExceptions(t *testing.T) {
+	outputs := map[string]string{
+		"print('ok')\n": ">>> print('ok')\r\nok\r\n>>> ",
+		"1/0\n": ">>> 1/0\r\nTraceback (most recent call last):\r\n" +
+			"  File \"<stdin>\", line 1, in <module>\r\n" +
+			"ZeroDivisionError: division by zero\r\n>>> ",
+		"def f(:\n": ">>> def f(:\r\n  File \"<stdin>\", line 1\r\n    def f(:\r\n          ^\r\n" +
+			"SyntaxError: invalid syntax\r\n>>> ",
+		"throw new Error('boom')\n": "> throw new Error('boom')\r\nUncaught Error: boom\r\n" +
+			"    at REPL2:1:7\r\n    at ContextifyScript.runInThisContext (node:vm:137:12)\r\n> ",
+		// Output that only looks like an error is not one.
+		"traceback.print_exc()\n": ">>> traceback.print_exc()\r\nTraceback (most recent call last):\r\n" +
+			"  File \"<stdin>\", line 2, in <module>\r\nKeyError: 'x'\r\nhandled\r\n>>> ",
+		"print('Summary')\n": ">>> print('Summary')\r\nTraceback (most recent call last):\r\nSummary\r\n>>> ",
+		"console.log(msg)\n": "> console.log(msg)\r\nTypeError: expected\r\nundefined\r\n> ",
+	}
+	_, client := newFakeContextAPI(t, func(input string) string { return outputs[input] })
+	sandbox := client.Sandbox("sb-fake")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := sandbox.Run(ctx, "python", "print('ok')\n")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Output != "ok\n" || result.Error != nil {
+		t.Fatalf("unexpected clean result: %q %+v", result.Output, result.Error)
+	}
+
+	result, err = sandbox.Run(ctx, "python", "1/0\n")
+	if err != nil {
+		t.Fatalf("run without fail-on-error returned error: %v", err)
+	}
+	if result.Error == nil || result.Error.Type != "ZeroDivisionError" || result.Error.Message != "division by zero" {
+		t.Fatalf("unexpected python error: %+v", result.Error)
+	}
+	if len(result.Error.Traceback) != 1 || result.Error.Traceback[0].File != "<stdin>" || result.Error.Traceback[0].Function != "<module>" {
+		t.Fatalf("unexpected python traceback: %+v", result.Error.Traceback)
+	}
+
+	_, err = sandbox.Run(ctx, "python", "def f(:\n", sandbox0.WithRunFailOnError())
+	var runErr *sandbox0.RunError
+	if !errors.As(err, &runErr) || runErr.Err.Type != "SyntaxError" {
+		t.Fatalf("expected syntax run error, got %v", err)
+	}
+	if frames := runErr.Err.Traceback; len(frames) != 1 || frames[0].Code != "def f(:" {
+		t.Fatalf("unexpected syntax traceback: %+v", frames)
+	}
+
+	result, err = sandbox.Run(ctx, "node", "throw new Error('boom')\n", sandbox0.WithRunFailOnError())
+	if !errors.As(err, &runErr) {
+		t.Fatalf("expected node run error, got %v", err)
+	}
+	if result.Error.Type != "Error" || result.Error.Message != "boom" || len(result.Error.Traceback) != 2 {
+		t.Fatalf("unexpected node error: %+v", result.Error)
+	}
+	if frame := result.Error.Traceback[1]; frame.Function != "ContextifyScript.runInThisContext" || frame.Line != 137 || frame.Column != 12 {
+		t.Fatalf("unexpected node frame: %+v", frame)
+	}
+	if !strings.HasPrefix(result.Error.Raw, "Uncaught Error: boom") {
+		t.Fatalf("unexpected raw error: %q", result.Error.Raw)
+	}
+
+	for language, input := range map[string]string{
+		"python": "traceback.print_exc()\n",
+		"node":   "console.log(msg)\n",
+	} {
+		result, err = sandbox.Run(ctx, language, input)
+		if err != nil || result.Error != nil {
+			t.Fatalf("unexpected error for %q: %v %+v", input, err, result.Error)
+		}
+	}
+	result, err = sandbox.Run(ctx, "python", "print('Summary')\n")
+	if err != nil || result.Error != nil {
+		t.Fatalf("unexpected error for printed text: %v %+v", err, result.Error)
+	}
+}
+
+func TestSandboxRunCustomPrompt(t *testing.T) {
+	_, client := newFakeContextAPI(t, func(input string) string {
+		return "calc> 1+1\r\n2\r\ncalc> "
+	})
+	sandbox := client.Sandbox("sb-fake")
+	result, err := sandbox.Run(context.Background(), "calc", "1+1\n", sandbox0.WithREPLPrompt("calc> "))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result.Output != "2\n" {
+		t.Fatalf("unexpected output: %q", result.Output)
+	}
+}
+
+func TestSandboxRunException(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := sandbox.Run(ctx, "python", "1/0\n", sandbox0.WithRunFailOnError())
+	var runErr *sandbox0.RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("expected run error, got %v (output %q)", err, result.OutputRaw)
+	}
+	if runErr.Err.Type != "ZeroDivisionError" {
+		t.Fatalf("unexpected exception: %+v", runErr.Err)
+	}
+}
@@ -6,7 +6,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -182,3 +189,133 @@ func isWsClosed(err error) bool {
 	}
 	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway)
 }
+
+func TestSandboxCmdExitStatus(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := sandbox.Cmd(ctx, "sh -c 'echo out; echo err >&2; exit 3'", sandbox0.WithCmdExitStatus(true))
+	var exitErr *sandbox0.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected exit error, got %v", err)
+	}
+	if exitErr.ExitCode != 3 || result.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d/%d", exitErr.ExitCode, result.ExitCode)
+	}
+	if result.Stdout == "" || result.StartedAt.IsZero() || result.Duration <= 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.ContextID != "" {
+		_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+	}
+
+	result, err = sandbox.Cmd(ctx, "echo ok", sandbox0.WithCmdExitStatus(true))
+	if err != nil {
+		t.Fatalf("cmd failed: %v", err)
+	}
+	if result.ExitCode != 0 || strings.Contains(result.OutputRaw, "__sandbox0_exit_") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+
+	// Without WithCmdExitStatus the command runs as-is and its status is unknown.
+	result, err = sandbox.Cmd(ctx, "echo ok")
+	if err != nil {
+		t.Fatalf("cmd failed: %v", err)
+	}
+	if result.ExitCode != -1 || !strings.Contains(result.OutputRaw, "ok") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+}
+
+func TestCmdExitStatusOptIn(t *testing.T) {
+	var mu sync.Mutex
+	var commands [][]string
+	var waits []bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Cmd struct {
+				Command []string `json:"command"`
+			} `json:"cmd"`
+			WaitUntilDone bool `json:"wait_until_done"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		commands = append(commands, req.Cmd.Command)
+		waits = append(waits, req.WaitUntilDone)
+		id := fmt.Sprintf("ctx-%d", len(commands))
+		mu.Unlock()
+		data := fakeContext(id, !req.WaitUntilDone)
+		if req.WaitUntilDone {
+			data["output_raw"] = "ok\r\n"
+		}
+		writeFakeSuccess(w, http.StatusCreated, data)
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		command := commands[len(commands)-1]
+		mu.Unlock()
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The wrapper script embeds the marker; a background child writes after it.
+		marker := regexp.MustCompile(`__sandbox0_exit_[0-9a-f]{16}__`).FindString(command[2])
+		for _, msg := range []sandbox0.ContextWebSocketResponse{
+			{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: "out\n"},
+			{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStderr, Data: "err\n" + marker + "3\nlate-err\n"},
+			{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: "late\n"},
+		} {
+			_ = conn.WriteJSON(msg)
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	})
+	mux.HandleFunc("DELETE /api/v1/sandboxes/{id}/contexts/{ctx}", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeSuccess(w, http.StatusOK, map[string]any{"deleted": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	sandbox := client.Sandbox("sb-fake")
+	ctx := context.Background()
+
+	result, err := sandbox.Cmd(ctx, "echo ok")
+	if err != nil {
+		t.Fatalf("cmd failed: %v", err)
+	}
+	if result.OutputRaw != "ok\r\n" || result.ExitCode != -1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(commands[0], []string{"echo", "ok"}) || !waits[0] {
+		t.Fatalf("expected the command to run as-is and be waited for, got %q wait=%v", commands[0], waits[0])
+	}
+
+	mu.Unlock()
+
+	result, err = sandbox.Cmd(ctx, "run-it", sandbox0.WithCmdExitStatus(true))
+	mu.Lock()
+	var exitErr *sandbox0.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 3 || result.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if commands[1][0] != "/bin/sh" || waits[1] {
+		t.Fatalf("expected a wrapped, streamed command, got %q wait=%v", commands[1], waits[1])
+	}
+	if result.Stdout != "out\nlate\n" || result.Stderr != "err\nlate-err\n" {
+		t.Fatalf("unexpected output: stdout %q stderr %q", result.Stdout, result.Stderr)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxRunFileAndScript(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	pyPath := filepath.Join(dir, "report.py")
+	if err := os.WriteFile(pyPath, []byte("import os, sys\nprint(sys.argv[1:], os.getcwd(), os.environ['GREETING'])\n"), 0o644); err != nil {
+		t.Fatalf("write script failed: %v", err)
+	}
+	var streamed strings.Builder
+	result, err := sandbox.RunFile(ctx, pyPath,
+		sandbox0.WithScriptArgs("a", "b c"),
+		sandbox0.WithScriptCWD("/tmp"),
+		sandbox0.WithScriptEnvVars(map[string]string{"GREETING": "hi"}),
+		sandbox0.WithScriptOutput(&streamed, nil),
+	)
+	if err != nil {
+		t.Fatalf("run file failed: %v", err)
+	}
+	if !strings.Contains(result.Stdout, "['a', 'b c'] /tmp hi") || streamed.String() != result.Stdout {
+		t.Fatalf("unexpected run file result: %q, streamed %q", result.Stdout, streamed.String())
+	}
+
+	shebangPath := filepath.Join(dir, "fail")
+	if err := os.WriteFile(shebangPath, []byte("#!/bin/sh\necho \"failing $1\" >&2\nexit 4\n"), 0o644); err != nil {
+		t.Fatalf("write script failed: %v", err)
+	}
+	result, err = sandbox.RunFile(ctx, shebangPath, sandbox0.WithScriptArgs("now"))
+	var exitErr *sandbox0.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 4 || !strings.Contains(result.Stderr, "failing now") {
+		t.Fatalf("expected exit code 4, got %+v, %v", result, err)
+	}
+
+	result, err = sandbox.RunScript(ctx, "sh", strings.NewReader("echo \"script $1\"\n"), []string{"arg"})
+	if err != nil {
+		t.Fatalf("run script failed: %v", err)
+	}
+	if result.Stdout != "script arg\n" {
+		t.Fatalf("unexpected run script output: %q", result.Stdout)
+	}
+
+	if _, err := sandbox.RunScript(ctx, "", strings.NewReader("echo no shebang\n"), nil); err == nil {
+		t.Fatalf("expected error for script without language or shebang")
+	}
+}
@@ -0,0 +1,71 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxShell(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	shell, err := sandbox.Shell(ctx)
+	if err != nil {
+		t.Fatalf("start shell failed: %v", err)
+	}
+	defer shell.Close()
+
+	result, err := shell.Exec(ctx, "mkdir -p /tmp/s0-shell && cd /tmp/s0-shell && pwd")
+	if err != nil {
+		t.Fatalf("cd failed: %v", err)
+	}
+	if result.Output != "/tmp/s0-shell\n" || result.Cwd != "/tmp/s0-shell" || shell.Cwd() != "/tmp/s0-shell" {
+		t.Fatalf("unexpected cd result: %+v", result)
+	}
+
+	if _, err := shell.Exec(ctx, "export GREETING='hello \"shell\"\nworld'"); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if got := shell.Env()["GREETING"]; got != "hello \"shell\"\nworld" {
+		t.Fatalf("unexpected tracked env: %q", got)
+	}
+	result, err = shell.Exec(ctx, "echo \"$GREETING\"; pwd")
+	if err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	if result.Output != "hello \"shell\"\nworld\n/tmp/s0-shell\n" {
+		t.Fatalf("state not kept between commands: %q", result.Output)
+	}
+
+	result, err = shell.Exec(ctx, "echo partial; (exit 3)")
+	var exitErr *sandbox0.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 3 || result.ExitCode != 3 || result.Output != "partial\n" {
+		t.Fatalf("expected exit code 3, got %+v, %v", result, err)
+	}
+
+	cmdCtx, cmdCancel := context.WithTimeout(ctx, time.Second)
+	_, err = shell.Exec(cmdCtx, "sleep 30")
+	cmdCancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	result, err = shell.Exec(ctx, "echo recovered")
+	if err != nil || result.Output != "recovered\n" {
+		t.Fatalf("shell did not recover after cancel: %+v, %v", result, err)
+	}
+
+	if _, err := shell.Exec(ctx, "exit 0"); !errors.Is(err, sandbox0.ErrShellClosed) {
+		t.Fatalf("expected shell closed after exit, got %v", err)
+	}
+}
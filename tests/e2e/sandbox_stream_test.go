@@ -0,0 +1,186 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	sandbox0 "github.com/sandbox0-ai/sdk-go"
+)
+
+func TestSandboxRunStream(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	defer sandbox.Close()
+
+	chunks, result := sandbox.RunStream(ctx, "python", "for i in range(3):\n    print('chunk-%d' % i)\n\n")
+	var streamed strings.Builder
+	for chunk, err := range chunks {
+		if err != nil {
+			t.Fatalf("run stream failed: %v", err)
+		}
+		streamed.WriteString(chunk.Data)
+	}
+	if !strings.Contains(streamed.String(), "chunk-2") {
+		t.Fatalf("unexpected streamed output: %q", streamed.String())
+	}
+	if result.OutputRaw != streamed.String() || !strings.Contains(result.Output, "chunk-0\nchunk-1\nchunk-2") {
+		t.Fatalf("unexpected result: %+v", *result)
+	}
+
+	for _, err := range chunks {
+		if err == nil {
+			t.Fatalf("expected error when reusing the iterator")
+		}
+	}
+
+	chunks, result = sandbox.RunStream(ctx, "python", "import time\nfor i in range(100):\n    print('tick', flush=True); time.sleep(0.1)\n\n")
+	for chunk := range chunks {
+		if strings.Contains(chunk.Data, "tick") {
+			break
+		}
+	}
+	if !strings.Contains(result.OutputRaw, "tick") {
+		t.Fatalf("expected output before break, got %q", result.OutputRaw)
+	}
+
+	// The REPL keeps running after the interrupt.
+	after, err := sandbox.Run(ctx, "python", "print('after-interrupt')")
+	if err != nil {
+		t.Fatalf("run after interrupt failed: %v", err)
+	}
+	if !strings.Contains(after.Output, "after-interrupt") {
+		t.Fatalf("unexpected output after interrupt: %q", after.Output)
+	}
+}
+
+func TestSandboxCmdStream(t *testing.T) {
+	cfg := loadE2EConfig(t)
+	token := e2eToken(t, cfg)
+	client := newClientWithToken(t, cfg, token)
+	sandbox := claimSandbox(t, client, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	chunks, result := sandbox.CmdStream(ctx, "sh -c 'for i in 1 2 3; do echo line$i; sleep 0.2; done; echo err >&2; exit 3'", sandbox0.WithCmdExitStatus(true))
+	var stdout strings.Builder
+	var streamErr error
+	for chunk, err := range chunks {
+		if err != nil {
+			streamErr = err
+			continue
+		}
+		if chunk.Source == sandbox0.OutputSourceStdout {
+			stdout.WriteString(chunk.Data)
+		}
+	}
+	var exitErr *sandbox0.ExitError
+	if !errors.As(streamErr, &exitErr) || exitErr.ExitCode != 3 {
+		t.Fatalf("expected exit error with code 3, got %v", streamErr)
+	}
+	if !strings.Contains(stdout.String(), "line3") {
+		t.Fatalf("unexpected streamed stdout: %q", stdout.String())
+	}
+	if result.ExitCode != 3 || !strings.Contains(result.Stderr, "err") {
+		t.Fatalf("unexpected result: %+v", *result)
+	}
+	if result.ContextID != "" {
+		_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+	}
+
+	chunks, result = sandbox.CmdStream(ctx, "sh -c 'while true; do echo tick; sleep 0.1; done'")
+	for range chunks {
+		break
+	}
+	if result.ExitCode == 0 || result.Duration > 30*time.Second {
+		t.Fatalf("expected command to be killed, got %+v", *result)
+	}
+	if result.ContextID != "" {
+		_, _ = sandbox.DeleteContext(ctx, result.ContextID)
+	}
+}
+
+func TestRunStreamSkipsReplayedOutput(t *testing.T) {
+	var mu sync.Mutex
+	var inputs, history []string
+	connections := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sandboxes/{id}/contexts", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeSuccess(w, http.StatusCreated, fakeContext("ctx-1", true))
+	})
+	mux.HandleFunc("GET /api/v1/sandboxes/{id}/contexts/{ctx}/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		connections++
+		replay := slices.Clone(history)
+		mu.Unlock()
+		// Every connection replays all earlier output.
+		for _, old := range replay {
+			_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: old})
+		}
+		for {
+			var req sandbox0.ContextWebSocketRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			out := strings.TrimSuffix(strings.TrimPrefix(req.Data, "print('"), "')\n") + "\r\n>>> "
+			mu.Lock()
+			inputs = append(inputs, req.Data)
+			history = append(history, out)
+			mu.Unlock()
+			_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageOutput, Source: sandbox0.OutputSourceStdout, Data: out})
+			_ = conn.WriteJSON(sandbox0.ContextWebSocketResponse{Type: sandbox0.ContextMessageDone, RequestID: req.RequestID})
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := sandbox0.NewClient(sandbox0.WithBaseURL(server.URL), sandbox0.WithToken("test-token"))
+	if err != nil {
+		t.Fatalf("create client failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sandbox := client.Sandbox("sb-1")
+	defer sandbox.Close()
+	for _, word := range []string{"first", "second"} {
+		chunks, result := sandbox.RunStream(ctx, "python", "print('"+word+"')")
+		var output strings.Builder
+		for chunk, err := range chunks {
+			if err != nil {
+				t.Fatalf("run stream failed: %v", err)
+			}
+			output.WriteString(chunk.Data)
+		}
+		if output.String() != word+"\r\n>>> " || result.ContextID != "ctx-1" {
+			t.Fatalf("expected only the output of %q, got %q in %q", word, output.String(), result.ContextID)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(inputs, []string{"print('first')\n", "print('second')\n"}) {
+		t.Fatalf("unexpected inputs: %q", inputs)
+	}
+	if connections != 1 {
+		t.Fatalf("expected one connection, got %d", connections)
+	}
+}
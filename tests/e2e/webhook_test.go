@@ -0,0 +1,304 @@
+//go:build e2e
+
+package sandbox0_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandbox0-ai/sdk-go/pkg/sandbox0webhook"
+)
+
+func TestWebhookHandlerVerifiesAndDispatches(t *testing.T) {
+	const secret = "webhook-secret"
+	var crashed []*sandbox0webhook.ProcessCrashedEvent
+	handler := sandbox0webhook.NewHandler(secret, sandbox0webhook.WithHandlers(sandbox0webhook.Handlers{
+		ProcessCrashed: func(_ context.Context, e *sandbox0webhook.ProcessCrashedEvent) error {
+			crashed = append(crashed, e)
+			return nil
+		},
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	body := []byte(`{"event_id":"evt-1","event_type":"process.crashed","sandbox_id":"sb-1","timestamp":"2026-01-01T00:00:00Z","data":{"context_id":"ctx-1","exit_code":2}}`)
+	post := func(body []byte, signedAt time.Time, sign bool) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("build request failed: %v", err)
+		}
+		if sign {
+			sandbox0webhook.SetSignatureHeaders(req.Header, secret, signedAt, body)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(body, time.Now(), false); status != http.StatusUnauthorized {
+		t.Fatalf("expected unsigned delivery to be rejected, got %d", status)
+	}
+	if status := post(body, time.Now().Add(-time.Hour), true); status != http.StatusUnauthorized {
+		t.Fatalf("expected stale delivery to be rejected, got %d", status)
+	}
+	if status := post(body, time.Now(), true); status != http.StatusNoContent {
+		t.Fatalf("expected delivery to be accepted, got %d", status)
+	}
+	if status := post(body, time.Now(), true); status != http.StatusOK {
+		t.Fatalf("expected replay to be acknowledged, got %d", status)
+	}
+	if len(crashed) != 1 {
+		t.Fatalf("expected exactly one dispatched event, got %d", len(crashed))
+	}
+	if crashed[0].ContextID != "ctx-1" || crashed[0].ExitCode == nil || *crashed[0].ExitCode != 2 {
+		t.Fatalf("unexpected event: %+v", crashed[0])
+	}
+
+	serve := func(handler http.Handler, header http.Header) int {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if status := serve(sandbox0webhook.NewHandler(""), nil); status != http.StatusInternalServerError {
+		t.Fatalf("expected handler without secret to reject, got %d", status)
+	}
+	if status := serve(sandbox0webhook.NewHandler("", sandbox0webhook.WithoutVerification()), nil); status != http.StatusNoContent {
+		t.Fatalf("expected unverified handler to accept, got %d", status)
+	}
+	scheme := sandbox0webhook.Scheme{
+		SignatureHeader: "X-Custom-Signature",
+		TimestampHeader: "X-Custom-Timestamp",
+		Sign: func(secret string, timestamp time.Time, body []byte) string {
+			return sandbox0webhook.Sign(secret+"-custom", timestamp, body)
+		},
+	}
+	custom := http.Header{}
+	scheme.SetHeaders(custom, secret, time.Now(), body)
+	if status := serve(sandbox0webhook.NewHandler(secret), custom); status != http.StatusUnauthorized {
+		t.Fatalf("expected default scheme to reject custom headers, got %d", status)
+	}
+	withScheme := sandbox0webhook.NewHandler(secret, sandbox0webhook.WithScheme(scheme))
+	if status := serve(withScheme, custom); status != http.StatusNoContent {
+		t.Fatalf("expected delivery with custom headers to be accepted, got %d", status)
+	}
+}
+
+func TestWebhookSimulatorSequenceWithFaults(t *testing.T) {
+	const secret = "webhook-secret"
+	events := make(chan sandbox0webhook.Event, 16)
+	server := httptest.NewServer(sandbox0webhook.NewHandler(secret, sandbox0webhook.WithEventChannel(events)))
+	defer server.Close()
+
+	sim := sandbox0webhook.NewSimulator(server.URL, secret, sandbox0webhook.WithSimulatorSandboxID("sb-sim"))
+	steps := []sandbox0webhook.Step{
+		sandbox0webhook.SandboxClaimedStep("default"),
+		sandbox0webhook.ProcessStartedStep("ctx-1").WithFaults(sandbox0webhook.FaultDuplicate),
+		sandbox0webhook.FileModifiedStep("/workspace/a.txt", "write"),
+		sandbox0webhook.SandboxPausedStep().WithFaults(sandbox0webhook.FaultBadSignature),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deliveries, err := sim.Run(ctx, sandbox0webhook.Reorder(steps, 0, 2, 1, 3))
+	if err != nil {
+		t.Fatalf("simulator run failed: %v", err)
+	}
+	if len(deliveries) != 5 {
+		t.Fatalf("expected 5 deliveries, got %d", len(deliveries))
+	}
+	if deliveries[4].StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected bad signature to be rejected, got %d", deliveries[4].StatusCode)
+	}
+	close(events)
+
+	var got []sandbox0webhook.EventType
+	for event := range events {
+		if event.Metadata().SandboxID != "sb-sim" {
+			t.Fatalf("unexpected sandbox ID: %q", event.Metadata().SandboxID)
+		}
+		got = append(got, event.Metadata().Type)
+	}
+	want := []sandbox0webhook.EventType{
+		sandbox0webhook.EventSandboxClaimed,
+		sandbox0webhook.EventFileModified,
+		sandbox0webhook.EventProcessStarted,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+	}
+
+	for _, step := range sandbox0webhook.AllEventSteps() {
+		payload, err := sim.Payload(step)
+		if err != nil {
+			t.Fatalf("build payload failed: %v", err)
+		}
+		if _, err := payload.Event(); err != nil {
+			t.Fatalf("decode %s failed: %v", step.Type, err)
+		}
+	}
+}
+
+func TestWebhookEventStoreDedupAndReplay(t *testing.T) {
+	const secret = "webhook-secret"
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := sandbox0webhook.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("open file store failed: %v", err)
+	}
+	server := httptest.NewServer(sandbox0webhook.NewHandler(secret, sandbox0webhook.WithEventStore(store)))
+	defer server.Close()
+
+	base := time.Now()
+	clock := base
+	sim := sandbox0webhook.NewSimulator(server.URL, secret, sandbox0webhook.WithSimulatorClock(func() time.Time { return clock }))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	started, err := sim.Payload(sandbox0webhook.ProcessStartedStep("ctx-1"))
+	if err != nil {
+		t.Fatalf("build payload failed: %v", err)
+	}
+	clock = base.Add(time.Second)
+	exited, err := sim.Payload(sandbox0webhook.ProcessExitedStep("ctx-1"))
+	if err != nil {
+		t.Fatalf("build payload failed: %v", err)
+	}
+	// Deliver out of order and with a retry of the first event.
+	for _, payload := range []sandbox0webhook.Payload{exited, started, exited} {
+		if d := sim.Deliver(ctx, payload, 0); d.Err != nil {
+			t.Fatalf("deliver failed: %v", d.Err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close store failed: %v", err)
+	}
+
+	reopened, err := sandbox0webhook.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen file store failed: %v", err)
+	}
+	defer reopened.Close()
+	if _, added, err := reopened.Append(ctx, exited); err != nil || added {
+		t.Fatalf("expected stored duplicate to be rejected after reopen, added=%v err=%v", added, err)
+	}
+
+	ordered, err := reopened.SandboxEvents(ctx, sim.SandboxID, 0)
+	if err != nil {
+		t.Fatalf("sandbox events failed: %v", err)
+	}
+	if len(ordered) != 2 || ordered[0].Payload.EventType != sandbox0webhook.EventProcessStarted {
+		t.Fatalf("expected events ordered by timestamp, got %+v", ordered)
+	}
+
+	var replayed []sandbox0webhook.EventType
+	cursor, err := sandbox0webhook.ReplayHandlers(ctx, reopened, 1, sandbox0webhook.Handlers{
+		ProcessStarted: func(_ context.Context, e *sandbox0webhook.ProcessStartedEvent) error {
+			replayed = append(replayed, e.Type)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if cursor != 2 || len(replayed) != 1 {
+		t.Fatalf("expected replay from cursor 1 to handle one event, cursor=%d replayed=%v", cursor, replayed)
+	}
+}
+
+func TestWebhookFileStoreRecovers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := sandbox0webhook.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("open file store failed: %v", err)
+	}
+	ctx := context.Background()
+	// A record larger than any fixed line limit.
+	large, _ := json.Marshal(map[string]string{"blob": strings.Repeat("x", 3<<20)})
+	for i, data := range []json.RawMessage{large, json.RawMessage(`{}`)} {
+		payload := sandbox0webhook.Payload{
+			EventID:   "evt-" + string(rune('a'+i)),
+			EventType: sandbox0webhook.EventProcessStarted,
+			SandboxID: "sb-1",
+			Timestamp: time.Now(),
+			Data:      data,
+		}
+		if _, _, err := store.Append(ctx, payload); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close store failed: %v", err)
+	}
+
+	// A crash during Append leaves the last record cut short.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open store file failed: %v", err)
+	}
+	_, _ = file.WriteString(`{"seq":3,"received_at":"2026-`)
+	_ = file.Close()
+
+	reopened, err := sandbox0webhook.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen file store failed: %v", err)
+	}
+	record, added, err := reopened.Append(ctx, sandbox0webhook.Payload{EventID: "evt-c", SandboxID: "sb-1"})
+	if err != nil || !added || record.Seq != 3 {
+		t.Fatalf("append after recovery failed: %+v %v %v", record, added, err)
+	}
+	_ = reopened.Close()
+
+	again, err := sandbox0webhook.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("open recovered store failed: %v", err)
+	}
+	defer again.Close()
+	records, err := again.Since(ctx, 0, 0)
+	if err != nil || len(records) != 3 || len(records[0].Payload.Data) != len(large) {
+		t.Fatalf("unexpected records after recovery: %d %v", len(records), err)
+	}
+}
+
+func TestWebhookBrokerDropsForFullSubscriber(t *testing.T) {
+	broker := sandbox0webhook.NewBroker()
+	slow, unsubscribeSlow := broker.Subscribe("sb-1", 1)
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := broker.Subscribe("", 2)
+	defer unsubscribeFast()
+
+	event := func(id string) sandbox0webhook.Event {
+		return &sandbox0webhook.SandboxPausedEvent{Meta: sandbox0webhook.Meta{ID: id, SandboxID: "sb-1"}}
+	}
+	if err := broker.Publish(context.Background(), event("evt-1")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := broker.Publish(context.Background(), event("evt-2")); !errors.Is(err, sandbox0webhook.ErrSubscriberFull) {
+		t.Fatalf("expected ErrSubscriberFull, got %v", err)
+	}
+	if got := (<-slow).Metadata().ID; got != "evt-1" || len(slow) != 0 {
+		t.Fatalf("expected slow subscriber to keep only evt-1, got %s and %d more", got, len(slow))
+	}
+	if len(fast) != 2 {
+		t.Fatalf("expected fast subscriber to receive both events, got %d", len(fast))
+	}
+}